@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"github.com/spunker/chess/state"
+)
+
+// Evaluator - anything that can score a position for the search, higher is better for white
+// the search may call Evaluate from several goroutines at once
+type Evaluator interface {
+	Evaluate(s *state.State) (float64, error)
+}
+
+// PawnValue - what a pawn is worth in the scores of the evaluator, a score divided by it is in pawns
+// that is the weight of the material term, evaluators without one are taken to score in pawns
+func PawnValue(evaluator Evaluator) float64 {
+	te, ok := evaluator.(termsEvaluator)
+	if !ok {
+		return 1
+	}
+	for _, wt := range te.Terms() {
+		if wt.Term.Name() == MaterialTerm.Name() && wt.Weight > 0 {
+			return wt.Weight
+		}
+	}
+	return 1
+}
+
+// Term - a single component of an evaluation (material, mobility, ...)
+// Score returns the raw value of the term for each color, the evaluation uses white minus black
+type Term interface {
+	Name() string
+	Score(s *state.State) (map[string]float64, error)
+}
+
+// TermFunc - turns a plain function into a Term
+type TermFunc struct {
+	TermName string
+	Func     func(s *state.State) (map[string]float64, error)
+}
+
+func (t TermFunc) Name() string {
+	return t.TermName
+}
+
+func (t TermFunc) Score(s *state.State) (map[string]float64, error) {
+	return t.Func(s)
+}
+
+// WeightedTerm - a term together with how much it counts in the evaluation
+type WeightedTerm struct {
+	Term   Term
+	Weight float64
+}
+
+// Terms - an evaluator that is the weighted sum of its terms
+// used to compose your own evaluation out of existing (or new) terms
+type Terms []WeightedTerm
+
+// Evaluate - sums weight * (white - black) over all terms
+func (terms Terms) Evaluate(s *state.State) (float64, error) {
+	result := 0.0
+	var pawns *PawnStructure // shared by the pawn structure terms, evaluated when the first one needs it
+	for _, wt := range terms {
+		if wt.Weight == 0 {
+			continue
+		}
+		var score map[string]float64
+		if pt, ok := wt.Term.(pawnStructureTerm); ok {
+			if pawns == nil {
+				pawns = EvalPawnStructure(s)
+			}
+			score = pt.field(pawns)
+		} else {
+			var err error
+			if score, err = wt.Term.Score(s); err != nil {
+				return 0, err
+			}
+		}
+		result += wt.Weight * (score["white"] - score["black"])
+	}
+	return result, nil
+}
+
+// MaterialTerm - material worth of the pieces of each color
+var MaterialTerm Term = TermFunc{
+	TermName: "material",
+	Func: func(s *state.State) (map[string]float64, error) {
+		acc := getAccumulator(s)
+		if acc == nil {
+			return fullMaterialStat(s), nil
+		}
+		if DebugIncremental {
+			if err := checkIncremental("material", acc.materialStat(), fullMaterialStat(s)); err != nil {
+				return nil, err
+			}
+		}
+		return acc.materialStat(), nil
+	},
+}
+
+// MobilityTerm - number of possible moves of the pieces of each color
+var MobilityTerm Term = TermFunc{
+	TermName: "mobility",
+	Func: func(s *state.State) (map[string]float64, error) {
+		return getMobilityStat(s)
+	},
+}
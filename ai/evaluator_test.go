@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+func TestTerms(t *testing.T) {
+	// white is a knight up, black has the only rook
+	s := mustFEN(t, "r3k3/8/8/8/8/8/8/1NN1K3 w - - 0 1")
+	knights := TermFunc{
+		TermName: "knights",
+		Func: func(s *state.State) (map[string]float64, error) {
+			result := map[string]float64{"white": 0, "black": 0}
+			for _, piece := range s.Board.GetPieces() {
+				if piece.Type == "knight" {
+					result[piece.Color]++
+				}
+			}
+			return result, nil
+		},
+	}
+	failing := TermFunc{
+		TermName: "failing",
+		Func: func(s *state.State) (map[string]float64, error) {
+			return nil, errors.New("should not be called")
+		},
+	}
+	material, err := MaterialTerm.Score(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evaluator := Terms{
+		{Term: knights, Weight: 0.5},
+		{Term: MaterialTerm, Weight: 2},
+		{Term: failing, Weight: 0}, // terms without weight are skipped
+	}
+	score, err := evaluator.Evaluate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := 0.5*(2-0) + 2*(material["white"]-material["black"])
+	if score != expected {
+		t.Errorf("Expected %v, got %v", expected, score)
+	}
+
+	evaluator[2].Weight = 1
+	if _, err := evaluator.Evaluate(s); err == nil {
+		t.Error("Expected the error of a term to be returned")
+	}
+}
+
+func TestPawnValue(t *testing.T) {
+	w := DefaultWeights()
+	if v := PawnValue(&w); v != w.Material {
+		t.Errorf("Expected the material weight %v, got %v", w.Material, v)
+	}
+	if v := PawnValue(Terms{{Term: MobilityTerm, Weight: 1}}); v != 1 {
+		t.Errorf("Expected an evaluator without material to score in pawns, got %v", v)
+	}
+}
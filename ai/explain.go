@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"fmt"
+
+	"github.com/spunker/chess/state"
+)
+
+// TermExplanation - one line of an evaluation breakdown
+type TermExplanation struct {
+	Name         string
+	Weight       float64
+	Raw          map[string]float64 // raw value of the term for each color
+	Contribution map[string]float64 // weight * raw for each color
+	Total        float64            // white minus black contribution, what the term adds to the evaluation
+}
+
+// termsEvaluator - evaluators that are made out of weighted terms can be explained
+type termsEvaluator interface {
+	Terms() Terms
+}
+
+// Terms - lets a Terms evaluator be explained like any other terms evaluator
+func (terms Terms) Terms() Terms {
+	return terms
+}
+
+// Explain - breaks the evaluation of the state down into its terms
+// the totals of all terms add up to what Evaluate returns
+func Explain(s *state.State, evaluator Evaluator) ([]*TermExplanation, error) {
+	te, ok := evaluator.(termsEvaluator)
+	if !ok {
+		return nil, fmt.Errorf("evaluator %T is not made out of terms and can't be explained", evaluator)
+	}
+	result := []*TermExplanation{}
+	for _, wt := range te.Terms() {
+		raw, err := wt.Term.Score(s)
+		if err != nil {
+			return nil, err
+		}
+		explanation := &TermExplanation{
+			Name:         wt.Term.Name(),
+			Weight:       wt.Weight,
+			Raw:          map[string]float64{},
+			Contribution: map[string]float64{},
+		}
+		for _, color := range []string{"white", "black"} {
+			explanation.Raw[color] = raw[color]
+			explanation.Contribution[color] = wt.Weight * raw[color]
+		}
+		explanation.Total = explanation.Contribution["white"] - explanation.Contribution["black"]
+		result = append(result, explanation)
+	}
+	return result, nil
+}
@@ -22,11 +22,13 @@ func getMaterialStat(s *state.State) (result map[string]int) {
 	return
 }
 
-// evalMaterial - evaluates the material balance of the state (uses getMaterialStat)
-// higher is better for white
-func evalMaterial(s *state.State) int {
+// fullMaterialStat - (helperfunction) getMaterialStat as used by the material term
+func fullMaterialStat(s *state.State) map[string]float64 {
 	stats := getMaterialStat(s)
-	return stats["white"] - stats["black"]
+	return map[string]float64{
+		"white": float64(stats["white"]),
+		"black": float64(stats["black"]),
+	}
 }
 
 // pieceMobility - (helperfunction) returns the mobility score of a piece (number of possible moves)
@@ -45,41 +47,93 @@ func pieceMobility(board *state.Board, piece *state.Piece) (int, error) {
 
 }
 
-// evalMobility - evaluates the mobility balance of the state (higher is better for white)
-func evalMobility(s *state.State) (int, error) {
-	result := 0
-	board := s.Board
-	for _, piece := range board.GetPieces() {
-		switch piece.Color {
-		case "white":
-			res, err := pieceMobility(s.Board, piece)
-			if err != nil {
-				return 0, err
-			}
-			result += res
-		case "black":
-			res, err := pieceMobility(s.Board, piece)
-			if err != nil {
-				return 0, err
-			}
-			result -= res
+// getMobilityStat - (helperfunction) returns a map with the total mobility for each color
+func getMobilityStat(s *state.State) (map[string]float64, error) {
+	result := map[string]float64{
+		"white": 0,
+		"black": 0,
+	}
+	for _, piece := range s.Board.GetPieces() {
+		res, err := pieceMobility(s.Board, piece)
+		if err != nil {
+			return nil, err
 		}
+		result[piece.Color] += float64(res)
 	}
 	return result, nil
 }
 
 // Weights - weights for different evaluation components
+// this is the default evaluator, *Weights implements Evaluator
 type Weights struct {
-	Material float64
-	Mobility float64
+	Material         float64 `json:"material"`
+	Mobility         float64 `json:"mobility"`
+	PieceSquare      float64 `json:"piece_square"`  // piece-square tables, tapered between middlegame and endgame
+	DoubledPawns     float64 `json:"doubled_pawns"` // pawn structure weaknesses, usually negative
+	IsolatedPawns    float64 `json:"isolated_pawns"`
+	BackwardPawns    float64 `json:"backward_pawns"`
+	PassedPawns      float64 `json:"passed_pawns"`
+	ConnectedPassers float64 `json:"connected_passers"`
+	PawnShield       float64 `json:"pawn_shield"` // king safety, open files and attackers are dangers so usually negative
+	OpenFiles        float64 `json:"open_files"`
+	KingAttackers    float64 `json:"king_attackers"`
+}
+
+// DefaultWeights - the weights the bot plays with unless told otherwise
+func DefaultWeights() Weights {
+	return Weights{
+		Material:         2.0,
+		Mobility:         0.5,
+		PieceSquare:      1.0,
+		DoubledPawns:     -0.2,
+		IsolatedPawns:    -0.2,
+		BackwardPawns:    -0.1,
+		PassedPawns:      0.5,
+		ConnectedPassers: 0.3,
+		PawnShield:       0.2,
+		OpenFiles:        -0.15,
+		KingAttackers:    -0.1,
+	}
+}
+
+// weightParam - a single weight together with the term it weighs
+type weightParam struct {
+	value *float64
+	term  Term
+}
+
+// params - all weights in evaluation order, used to build the terms and by the tuner
+func (w *Weights) params() []weightParam {
+	return []weightParam{
+		{&w.Material, MaterialTerm},
+		{&w.Mobility, MobilityTerm},
+		{&w.PieceSquare, PieceSquareTerm},
+		{&w.DoubledPawns, DoubledPawnsTerm},
+		{&w.IsolatedPawns, IsolatedPawnsTerm},
+		{&w.BackwardPawns, BackwardPawnsTerm},
+		{&w.PassedPawns, PassedPawnsTerm},
+		{&w.ConnectedPassers, ConnectedPassersTerm},
+		{&w.PawnShield, PawnShieldTerm},
+		{&w.OpenFiles, OpenFilesTerm},
+		{&w.KingAttackers, KingAttackersTerm},
+	}
+}
+
+// Terms - the weighted terms that make up the default evaluation
+func (w *Weights) Terms() Terms {
+	result := Terms{}
+	for _, param := range w.params() {
+		result = append(result, WeightedTerm{Term: param.term, Weight: *param.value})
+	}
+	return result
+}
+
+// Evaluate - implements Evaluator using EvalState
+func (w *Weights) Evaluate(s *state.State) (float64, error) {
+	return EvalState(s, w)
 }
 
 // EvalState - evaluates the state using weighted sum of different heuristics
 func EvalState(s *state.State, weights *Weights) (float64, error) {
-	evalMat := evalMaterial(s)
-	evalMob, err := evalMobility(s)
-	if err != nil {
-		return 0, err
-	}
-	return weights.Material*float64(evalMat) + weights.Mobility*float64(evalMob), nil
+	return weights.Terms().Evaluate(s)
 }
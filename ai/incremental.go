@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/spunker/chess/state"
+)
+
+// Incremental evaluation
+// material and piece-square scores are kept up to date while moves are applied (see state.Accumulator),
+// so evaluating them at a leaf doesn't have to walk the board
+
+// DebugIncremental - when set, every incrementally computed term is checked against a full recomputation
+// and the evaluation returns an error if they disagree, slow, only meant for testing
+var DebugIncremental = false
+
+// IncrementalEvaluator - an evaluator that can keep part of its work up to date during the search
+// the search attaches a fresh accumulator to (a copy of) the root position
+type IncrementalEvaluator interface {
+	Evaluator
+	NewAccumulator() state.Accumulator
+}
+
+// colorTally - the incrementally kept values of one color
+type colorTally struct {
+	material int
+	mg       float64 // piece-square middlegame score
+	eg       float64 // piece-square endgame score
+}
+
+// evalAccumulator - implements state.Accumulator for the material and piece-square terms
+type evalAccumulator struct {
+	white colorTally
+	black colorTally
+	phase int
+}
+
+func (acc *evalAccumulator) tally(color string) *colorTally {
+	if color == "white" {
+		return &acc.white
+	}
+	return &acc.black
+}
+
+func (acc *evalAccumulator) Add(piece *state.Piece, pos state.Position) {
+	t := acc.tally(piece.Color)
+	mg, eg := pieceSquareValue(piece, pos)
+	t.material += piece.Worth
+	t.mg += mg
+	t.eg += eg
+	acc.phase += phaseWorth[piece.Type]
+}
+
+func (acc *evalAccumulator) Remove(piece *state.Piece, pos state.Position) {
+	t := acc.tally(piece.Color)
+	mg, eg := pieceSquareValue(piece, pos)
+	t.material -= piece.Worth
+	t.mg -= mg
+	t.eg -= eg
+	acc.phase -= phaseWorth[piece.Type]
+}
+
+func (acc *evalAccumulator) Copy() state.Accumulator {
+	copy := *acc
+	return &copy
+}
+
+// NewAccumulator - the default evaluation keeps material and piece-square scores incrementally
+func (w *Weights) NewAccumulator() state.Accumulator {
+	return &evalAccumulator{}
+}
+
+// getAccumulator - returns the evaluation accumulator of the state, or nil if it doesn't have one
+func getAccumulator(s *state.State) *evalAccumulator {
+	acc, _ := s.Board.Accumulator().(*evalAccumulator)
+	return acc
+}
+
+// materialStat - material for each color from the accumulator
+func (acc *evalAccumulator) materialStat() map[string]float64 {
+	return map[string]float64{
+		"white": float64(acc.white.material),
+		"black": float64(acc.black.material),
+	}
+}
+
+// pieceSquareStat - tapered piece-square score for each color from the accumulator
+func (acc *evalAccumulator) pieceSquareStat() map[string]float64 {
+	phase := float64(min(acc.phase, maxPhase)) / maxPhase
+	return map[string]float64{
+		"white": phase*acc.white.mg + (1-phase)*acc.white.eg,
+		"black": phase*acc.black.mg + (1-phase)*acc.black.eg,
+	}
+}
+
+// checkIncremental - compares an incrementally kept term with its full recomputation
+func checkIncremental(name string, incremental map[string]float64, full map[string]float64) error {
+	for _, color := range []string{"white", "black"} {
+		if math.Abs(incremental[color]-full[color]) > 1e-9 {
+			return fmt.Errorf("incremental %v for %v is %v, full recomputation gives %v", name, color, incremental[color], full[color])
+		}
+	}
+	return nil
+}
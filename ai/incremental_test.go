@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+func TestIncrementalMatchesFull(t *testing.T) {
+	s := mustFEN(t, "r3k2r/1P3ppp/8/3pP3/8/8/PP3PPP/R3K2R w KQkq - 0 1")
+	w := DefaultWeights()
+	s.Board.SetAccumulator(w.NewAccumulator())
+
+	// promotion with capture, castling on both sides and a normal capture
+	for _, alg := range []string{"B7-A8", "E8-G8", "E1-C1", "D5-D4", "A8-F8", "G8-F8"} {
+		if _, err := s.ApplyMove(state.FromAlgebraicToMove(alg)); err != nil {
+			t.Fatalf("Failed to apply move %s: %v", alg, err)
+		}
+		acc := getAccumulator(s)
+		if err := checkIncremental("material", acc.materialStat(), fullMaterialStat(s)); err != nil {
+			t.Errorf("After %v: %v", alg, err)
+		}
+		if err := checkIncremental("piece-square", acc.pieceSquareStat(), getPieceSquareStat(s)); err != nil {
+			t.Errorf("After %v: %v", alg, err)
+		}
+	}
+}
+
+func TestDebugIncrementalSearch(t *testing.T) {
+	DebugIncremental = true
+	defer func() { DebugIncremental = false }()
+
+	w := DefaultWeights()
+	s := mustFEN(t, "r3k2r/1P3ppp/8/3pP3/8/8/PP3PPP/R3K2R w KQkq - 0 1")
+	if _, err := Search(s, &w, &SearchOptions{Depth: 2, Threads: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
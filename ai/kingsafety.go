@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"github.com/spunker/chess/state"
+)
+
+// King safety evaluation
+// the pawn shield and open files only matter while there are pieces left to attack the king,
+// so they are scaled by the game phase, the king zone attacks scale themselves by the number of attackers
+
+// attackerWeight - how dangerous a piece attacking the king zone is
+var attackerWeight = map[string]float64{
+	"pawn":   0.5,
+	"knight": 2,
+	"bishop": 2,
+	"rook":   3,
+	"queen":  5,
+}
+
+// attackerCountScale - one attacker is rarely dangerous, several together are
+var attackerCountScale = []float64{0, 0, 0.5, 0.75, 0.88, 0.94, 0.97, 0.99}
+
+// KingSafety - the raw king safety terms for each color
+// PawnShield counts own pawns in front of the king (good), OpenFiles counts missing pawns on the
+// king's and adjacent files (bad), KingAttackers is the weighted pressure on the king zone (bad)
+type KingSafety struct {
+	PawnShield    map[string]float64
+	OpenFiles     map[string]float64
+	KingAttackers map[string]float64
+}
+
+// pawnOnFile - (helperfunction) checks whether there is a pawn of the given color on file x
+func pawnOnFile(board *state.Board, x int, color string) bool {
+	for y := range 8 {
+		piece := board.Grid[y][x]
+		if piece != nil && piece.Type == "pawn" && piece.Color == color {
+			return true
+		}
+	}
+	return false
+}
+
+// pawnShield - own pawns on the king's and adjacent files, one or two ranks in front of the king
+// a pawn directly in front of the king counts fully, one that has advanced a square counts half
+func pawnShield(board *state.Board, king state.Position, color string) (result float64) {
+	for x := king.X - 1; x <= king.X+1; x++ {
+		for distance, value := range []float64{1, 0.5} {
+			pos := state.Position{X: x, Y: king.Y + (distance+1)*forward(color)}
+			piece, err := board.GetPiece(&pos)
+			if err == nil && piece != nil && piece.Type == "pawn" && piece.Color == color {
+				result += value
+				break
+			}
+		}
+	}
+	return
+}
+
+// openFiles - files around the king without own pawns, files without any pawns count double
+func openFiles(board *state.Board, king state.Position, color string) (result float64) {
+	for x := king.X - 1; x <= king.X+1; x++ {
+		if x < 0 || x > 7 || pawnOnFile(board, x, color) {
+			continue
+		}
+		result++
+		if !pawnOnFile(board, x, opponent(color)) {
+			result++
+		}
+	}
+	return
+}
+
+// kingZone - the squares around the king plus the ones in front of those
+func kingZone(king state.Position, color string) (result []*state.Position) {
+	for x := king.X - 1; x <= king.X+1; x++ {
+		for y := king.Y - 1; y <= king.Y+1; y++ {
+			result = append(result, &state.Position{X: x, Y: y})
+		}
+		result = append(result, &state.Position{X: x, Y: king.Y + 2*forward(color)})
+	}
+	return result
+}
+
+// kingAttackers - weighted number of enemy pieces attacking at least one square of the king zone
+func kingAttackers(board *state.Board, king state.Position, color string) float64 {
+	zone := kingZone(king, color)
+	weight := 0.0
+	count := 0
+	for _, piece := range board.GetPieces() {
+		if piece.Color == color || piece.Type == "king" {
+			continue
+		}
+		for _, pos := range zone {
+			if piece.Attacks(board, pos) {
+				weight += attackerWeight[piece.Type]
+				count++
+				break
+			}
+		}
+	}
+	return weight * attackerCountScale[min(count, len(attackerCountScale)-1)]
+}
+
+// EvalKingSafety - returns all king safety terms of the state
+func EvalKingSafety(s *state.State) *KingSafety {
+	result := &KingSafety{
+		PawnShield:    map[string]float64{"white": 0, "black": 0},
+		OpenFiles:     map[string]float64{"white": 0, "black": 0},
+		KingAttackers: map[string]float64{"white": 0, "black": 0},
+	}
+	phase := GamePhase(s)
+	for _, color := range []string{"white", "black"} {
+		kingPos := s.Board.FindPiece("king", color)
+		if len(kingPos) == 0 {
+			continue
+		}
+		king := *kingPos[0]
+		result.PawnShield[color] = phase * pawnShield(s.Board, king, color)
+		result.OpenFiles[color] = phase * openFiles(s.Board, king, color)
+		result.KingAttackers[color] = kingAttackers(s.Board, king, color)
+	}
+	return result
+}
+
+// kingSafetyTerm - (helperfunction) creates a term out of one field of the king safety
+func kingSafetyTerm(name string, field func(k *KingSafety) map[string]float64) Term {
+	return TermFunc{
+		TermName: name,
+		Func: func(s *state.State) (map[string]float64, error) {
+			return field(EvalKingSafety(s)), nil
+		},
+	}
+}
+
+// king safety terms, open files and king attackers are dangers so they should get a negative weight
+var (
+	PawnShieldTerm    = kingSafetyTerm("pawn shield", func(k *KingSafety) map[string]float64 { return k.PawnShield })
+	OpenFilesTerm     = kingSafetyTerm("open files near king", func(k *KingSafety) map[string]float64 { return k.OpenFiles })
+	KingAttackersTerm = kingSafetyTerm("king attackers", func(k *KingSafety) map[string]float64 { return k.KingAttackers })
+)
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+func mustFEN(t *testing.T, fen string) *state.State {
+	t.Helper()
+	s, err := state.CreateStateFEN(fen)
+	if err != nil {
+		t.Fatalf("Failed to parse fen %q: %v", fen, err)
+	}
+	return s
+}
+
+// diff - white minus black for one of the king safety terms
+func diff(m map[string]float64) float64 {
+	return m["white"] - m["black"]
+}
+
+func TestPawnShield(t *testing.T) {
+	// white castled behind f2 g2 h2, black castled without any pawns
+	s := mustFEN(t, "r2q1rk1/8/8/8/8/8/5PPP/R2Q1RK1 w - - 0 1")
+	k := EvalKingSafety(s)
+	if diff(k.PawnShield) <= 0 {
+		t.Errorf("Expected white to have the better pawn shield, got %v", k.PawnShield)
+	}
+	if diff(k.OpenFiles) >= 0 {
+		t.Errorf("Expected black to have more open files near the king, got %v", k.OpenFiles)
+	}
+
+	// pushing the g-pawn weakens the shield
+	pushed := mustFEN(t, "r2q1rk1/8/8/8/6P1/8/5P1P/R2Q1RK1 w - - 0 1")
+	if EvalKingSafety(pushed).PawnShield["white"] >= k.PawnShield["white"] {
+		t.Errorf("Expected g2-g4 to weaken the pawn shield")
+	}
+}
+
+func TestPawnShieldEndgame(t *testing.T) {
+	// without pieces the pawn shield doesn't matter anymore
+	s := mustFEN(t, "6k1/8/8/8/8/8/5PPP/6K1 w - - 0 1")
+	k := EvalKingSafety(s)
+	if k.PawnShield["white"] != 0 || k.OpenFiles["black"] != 0 {
+		t.Errorf("Expected no king safety in a pawn endgame, got %v %v", k.PawnShield, k.OpenFiles)
+	}
+}
+
+func TestKingAttackers(t *testing.T) {
+	// black queen and knight swarm the white king
+	s := mustFEN(t, "6kr/5pp1/8/8/6nq/8/5PP1/6K1 w - - 0 1")
+	k := EvalKingSafety(s)
+	if diff(k.KingAttackers) <= 0 {
+		t.Errorf("Expected the white king to be under attack, got %v", k.KingAttackers)
+	}
+	if k.KingAttackers["black"] != 0 {
+		t.Errorf("Expected the black king not to be under attack, got %v", k.KingAttackers["black"])
+	}
+
+	// a single attacker isn't counted as an attack yet
+	single := mustFEN(t, "6k1/5ppp/8/8/7q/8/5PP1/6K1 w - - 0 1")
+	if EvalKingSafety(single).KingAttackers["white"] != 0 {
+		t.Errorf("Expected a lone queen not to count as a king attack")
+	}
+}
+
+func TestKingSafetyWeights(t *testing.T) {
+	// with the default weights the exposed king should cost white
+	w := DefaultWeights()
+	safety := Terms{
+		{Term: PawnShieldTerm, Weight: w.PawnShield},
+		{Term: OpenFilesTerm, Weight: w.OpenFiles},
+		{Term: KingAttackersTerm, Weight: w.KingAttackers},
+	}
+	score, err := safety.Evaluate(mustFEN(t, "6kr/5ppp/8/8/6nq/8/8/3Q2K1 w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score >= 0 {
+		t.Errorf("Expected a negative king safety score for white, got %v", score)
+	}
+}
@@ -1,29 +1,240 @@
 package ai
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/spunker/chess/state"
 )
 
+// SearchResult - everything SelectMove found out about a position
+// PV is the principal variation: the line the engine expects, starting with Move
+type SearchResult struct {
+	Move     *state.Move
+	Score    float64
+	PV       []*state.Move
+	Depth    int           // depth that was searched to completion
+	SelDepth int           // deepest ply that was actually visited
+	Nodes    int           // number of positions visited
+	Time     time.Duration // wall clock time spent searching
+	NPS      int           // nodes per second
+	Mate     bool          // true if Score is a mate score
+	MateIn   int           // moves until mate, positive when white delivers it (only set if Mate)
+	SearchStats
+
+	rootMoves []rootMove // every root move with its own score, in generation order
+	pawnValue float64    // what a pawn is worth in Score, see PawnValue
+}
+
+// MateScore - score of a position where white has delivered checkmate (black: -MateScore)
+// mates further away are worth one point less per ply, so the search prefers the fastest mate
+const MateScore = 100000.0
+
+// maxMatePly - longest mate (in plies) that is still recognised as a mate score
+const maxMatePly = 1000
+
+// IsMateScore - checks whether a score means a forced mate rather than an evaluation
+func IsMateScore(score float64) bool {
+	return math.Abs(score) >= MateScore-maxMatePly
+}
+
+// mateIn - converts a mate score to the number of moves until mate, positive when white mates
+func mateIn(score float64) int {
+	plies := int(MateScore - math.Abs(score))
+	moves := (plies + 1) / 2
+	if score < 0 {
+		return -moves
+	}
+	return moves
+}
+
+// ScoreString - the score as shown to the user in pawns from white's side, mates are shown as #N (#-N when black mates)
+func (r *SearchResult) ScoreString() string {
+	if r.Mate {
+		return fmt.Sprintf("#%v", r.MateIn)
+	}
+	pawn := r.pawnValue
+	if pawn <= 0 {
+		pawn = 1
+	}
+	return fmt.Sprintf("%+.2f", r.Score/pawn)
+}
+
+// PVString - the principal variation in algebraic notation, separated by spaces
+func (r *SearchResult) PVString() (result string) {
+	for i, move := range r.PV {
+		if i > 0 {
+			result += " "
+		}
+		result += move.ToAlgebraic()
+	}
+	return
+}
+
+// searcher - holds the bookkeeping of a single search so minimax doesn't need a dozen parameters
+type searcher struct {
+	evaluator Evaluator
+	limits    *searchLimits
+	drawScore float64 // score of a drawn position, includes the contempt of the side to move at the root
+	nullMove  bool
+	lmr       bool
+	futility  bool
+	pawnValue float64             // worth of a pawn in the scores of the evaluator, margins are given in pawns
+	qDepth    int                 // plies of captures searched after the depth ran out
+	table     *TranspositionTable // nil searches without one
+	keySalt   uint64              // mixed into the table keys, scores that contain draws depend on the draw score
+	trace     *tracer             // nil doesn't trace
+	nodes     int
+	selDepth  int
+	stats     SearchStats
+}
+
+// errAborted - returned up through the search when a limit is hit, the unfinished iteration is thrown away
+var errAborted = errors.New("search aborted")
+
+// searchLimits - limits shared by all goroutines of one search
+type searchLimits struct {
+	maxNodes int64 // 0 means no limit
+	nodes    atomic.Int64
+	stop     <-chan struct{}
+	expired  <-chan struct{} // closed when the time of the search is up, nil without a time limit
+	enforced bool            // the first iteration is never aborted, so there always is a move to play
+}
+
+// visit - counts a node, returns false once the search has to stop
+func (l *searchLimits) visit() bool {
+	nodes := l.nodes.Add(1)
+	if !l.enforced {
+		return true
+	}
+	if l.maxNodes > 0 && nodes > l.maxNodes {
+		return false
+	}
+	select {
+	case <-l.stop:
+		return false
+	case <-l.expired:
+		return false
+	default:
+		return true
+	}
+}
+
+// newSearcher - creates a searcher for a search from the point of view of rootTurn
+func newSearcher(evaluator Evaluator, opts *SearchOptions, rootTurn string, limits *searchLimits) *searcher {
+	sr := &searcher{
+		evaluator: evaluator,
+		limits:    limits,
+		nullMove:  !opts.NoNullMove,
+		lmr:       !opts.NoLMR,
+		futility:  !opts.NoFutility,
+		pawnValue: PawnValue(evaluator),
+		qDepth:    opts.Quiescence,
+		table:     opts.Table,
+	}
+	if opts.Trace != nil {
+		sr.trace = &tracer{w: opts.Trace, maxPly: opts.TracePly}
+	}
+	if rootTurn == "white" {
+		sr.drawScore = -opts.Contempt
+	} else {
+		sr.drawScore = opts.Contempt
+	}
+	sr.keySalt = drawScoreSalt(sr.drawScore)
+	return sr
+}
+
+// terminalScore - scores a position without legal moves (or a dead draw)
+// checkmate is scored as a mate in ply, everything else is a draw
+func (sr *searcher) terminalScore(s *state.State, ply int) (float64, error) {
+	isMate, err := s.IsCheckmate()
+	if err != nil {
+		return 0, err
+	}
+	if !isMate {
+		return sr.drawScore, nil
+	}
+	if s.Turn == "white" {
+		return -(MateScore - float64(ply)), nil
+	}
+	return MateScore - float64(ply), nil
+}
+
 // minimax - minimax algorithm with alpha-beta pruning
-func minimax(s *state.State, depth int, max bool, alpha float64, beta float64, weights *Weights) (float64, error) {
+// returns the evaluation together with the principal variation from this node
+// allowNull is false right after a null move, two passes in a row would search the same position again
+func (sr *searcher) minimax(s *state.State, depth int, ply int, max bool, alpha float64, beta float64, allowNull bool) (float64, []*state.Move, error) {
+	sr.nodes++
+	if ply > sr.selDepth {
+		sr.selDepth = ply
+	}
+	if !sr.limits.visit() {
+		return 0, nil, errAborted
+	}
+
 	// base case for recursion
 	isOver, err := s.IsGameOver()
 	if err != nil {
-		return 0, err
+		return 0, nil, err
+	}
+	if isOver || s.IsInsufficientMaterial() {
+		eval, err := sr.terminalScore(s, ply)
+		return eval, nil, err
+	}
+	if depth == 0 {
+		return sr.quiesce(s, 0, ply, max, alpha, beta)
+	}
+
+	// a position that was searched deep enough before doesn't have to be searched again
+	var key uint64
+	var ttMove *state.Move
+	alphaOrig, betaOrig := alpha, beta
+	if sr.table != nil {
+		key = positionKey(s) ^ sr.keySalt
+		sr.stats.TTProbes++
+		if entry, ok := sr.table.probe(key, ply); ok {
+			sr.stats.TTHits++
+			ttMove = entry.move
+			if entry.cutoff(depth, alpha, beta) {
+				sr.stats.TTCutoffs++
+				var pv []*state.Move
+				if entry.bound == boundExact {
+					pv = sr.tablePV(s, entry.move, depth)
+				}
+				return entry.score, pv, nil
+			}
+		}
 	}
-	if depth == 0 || isOver {
-		eval, err := EvalState(s, weights)
+
+	inCheck := s.InCheck()
+	if allowNull && sr.nullMove && !inCheck {
+		score, cutoff, err := sr.nullMoveCutoff(s, depth, ply, max, alpha, beta)
+		if err != nil || cutoff {
+			if cutoff {
+				sr.stats.NullMoveCutoffs++
+			}
+			return score, nil, err
+		}
+	}
+
+	// near the leaves, quiet moves can't make up for a static evaluation far outside the window
+	futile := false
+	if sr.futility && !inCheck && depth < len(futilityMargins) {
+		futile, err = sr.isFutile(s, depth, max, alpha, beta)
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
-		return eval, nil
 	}
 
 	// check if maximizing or minimizing player
 	var evaln float64
+	var pv []*state.Move
 	if max {
 		evaln = math.Inf(-1)
 	} else {
@@ -33,88 +244,328 @@ func minimax(s *state.State, depth int, max bool, alpha float64, beta float64, w
 	// iterate through all legal moves fetched from the state
 	legalMoves, err := s.GetLegalMoves()
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
-	for _, move := range legalMoves {
+	searched := 0
+	for i, move := range orderMoves(s, legalMoves, ttMove) {
+		tactical := isTactical(s, move)
+
 		// try the move (simulate on a copy)
 		copyState, err := s.Copy()
 		if err != nil {
-			return evaln, err
+			return evaln, nil, err
 		}
 		_, err = copyState.ApplyMove(move)
 		if err != nil {
-			return evaln, err
+			return evaln, nil, err
 		}
+		quiet := !tactical && !copyState.InCheck()
+		if futile && quiet && pv != nil {
+			continue
+		}
+		searched++
 
-		// recursively call minimax on the new state
-		currentEvaln, err := minimax(copyState, depth-1, !max, alpha, beta, weights)
+		// late quiet moves are searched a ply shallower first, only when they look good they get the full depth
+		sr.trace.enter(ply+1, traceMove(move), depth-1, alpha, beta)
+		var currentEvaln float64
+		var childPV []*state.Move
+		reduced := sr.lmr && quiet && !inCheck && depth >= lmrMinDepth && i >= lmrFullMoves
+		if reduced {
+			currentEvaln, childPV, err = sr.minimax(copyState, depth-2, ply+1, !max, alpha, beta, true)
+			reduced = err == nil && ((max && currentEvaln <= alpha) || (!max && currentEvaln >= beta))
+		}
+		if !reduced && err == nil {
+			// recursively call minimax on the new state
+			currentEvaln, childPV, err = sr.minimax(copyState, depth-1, ply+1, !max, alpha, beta, true)
+		}
+		sr.trace.leave(ply+1, traceMove(move), currentEvaln, err)
 		if err != nil {
-			return currentEvaln, fmt.Errorf("error evalutating %v", move.ToAlgebraic())
+			return currentEvaln, nil, fmt.Errorf("error evalutating %v: %w", move.ToAlgebraic(), err)
 		}
 
 		// update evaln, alpha, beta based on maximizing or minimizing player
 		if max {
-			evaln = math.Max(evaln, currentEvaln)
-			alpha = math.Max(alpha, evaln)
-			if beta <= alpha {
-				break
+			if currentEvaln > evaln || pv == nil {
+				evaln = currentEvaln
+				pv = append([]*state.Move{move}, childPV...)
 			}
+			alpha = math.Max(alpha, evaln)
 		} else {
-			evaln = math.Min(evaln, currentEvaln)
+			if currentEvaln < evaln || pv == nil {
+				evaln = currentEvaln
+				pv = append([]*state.Move{move}, childPV...)
+			}
 			beta = math.Min(beta, evaln)
-			if beta <= alpha {
-				break
+		}
+		if beta <= alpha {
+			sr.stats.BetaCutoffs++
+			if searched == 1 {
+				sr.stats.FirstMoveCutoffs++
 			}
+			break
+		}
+	}
+
+	if sr.table != nil {
+		b := boundExact
+		if evaln <= alphaOrig {
+			b = boundUpper
+		} else if evaln >= betaOrig {
+			b = boundLower
 		}
+		sr.table.store(key, ply, depth, evaln, b, pv[0])
 	}
-	return evaln, nil
+	return evaln, pv, nil
 }
 
-// SelectMove - selects the best move using minimax algorithm
-func SelectMove(s *state.State, depth int, weights *Weights) (*state.Move, float64, error) {
+// SearchOptions - settings for a search
+// the zero value is a single threaded search to depth 0
+type SearchOptions struct {
+	Depth    int
+	Threads  int           // number of goroutines the root moves are split over, 1 or less searches single threaded
+	Contempt float64       // how much the side to move dislikes a draw, 0 scores draws as equal
+	Nodes    int           // stop after searching this many nodes (0 = no limit), the deepest finished depth is returned
+	MoveTime time.Duration // stop after this long (0 = no limit), every pass of SearchMultiPV gets the whole of it
+
+	Quiescence int                 // plies of captures and promotions searched past Depth (0 = evaluate right away)
+	Table      *TranspositionTable // remembers positions across the search (and across searches), nil for none
+
+	Trace    io.Writer // writes the search tree to Trace (single threaded only), nil for no trace
+	TracePly int       // deepest ply that is traced
+
+	Stop <-chan struct{}     // closing it ends the search, the deepest finished depth is returned
+	Info func(*SearchResult) // called after every finished depth (the search deepens iteratively when set)
+
+	// selective search is on by default, these turn the techniques off one by one (to measure what they do)
+	NoNullMove bool
+	NoLMR      bool
+	NoFutility bool
+}
+
+// SelectMove - selects the best move using minimax algorithm (single threaded)
+func SelectMove(s *state.State, depth int, evaluator Evaluator) (*SearchResult, error) {
+	return Search(s, evaluator, &SearchOptions{Depth: depth, Threads: 1})
+}
+
+// SelectMoves - multi-PV search (single threaded), see SearchMultiPV
+func SelectMoves(s *state.State, depth int, evaluator Evaluator, n int) ([]*SearchResult, error) {
+	return SearchMultiPV(s, evaluator, &SearchOptions{Depth: depth, Threads: 1}, n)
+}
+
+// Search - selects the best move with the given options
+func Search(s *state.State, evaluator Evaluator, opts *SearchOptions) (*SearchResult, error) {
+	return searchIterative(s, evaluator, opts, nil)
+}
+
+// SearchMultiPV - returns the best n moves each with their own score and line
+// every pass is a full root search that skips the moves reported by the previous passes
+func SearchMultiPV(s *state.State, evaluator Evaluator, opts *SearchOptions, n int) ([]*SearchResult, error) {
+	results := []*SearchResult{}
+	exclude := []*state.Move{}
+	for len(results) < n {
+		result, err := searchIterative(s, evaluator, opts, exclude)
+		if err != nil {
+			return nil, err
+		}
+		if result.Move == nil { // no moves left to report
+			break
+		}
+		results = append(results, result)
+		exclude = append(exclude, result.Move)
+	}
+	return results, nil
+}
+
+// searchIterative - searches to opts.Depth, with a node limit or a stop channel this is done by iterative
+// deepening so the last depth that finished within the limit can be returned
+func searchIterative(s *state.State, evaluator Evaluator, opts *SearchOptions, exclude []*state.Move) (*SearchResult, error) {
+	// search a copy of the root with the accumulator of the evaluator attached, every position below
+	// it is a copy of a copy, so they all keep their incremental terms up to date
+	if ie, ok := evaluator.(IncrementalEvaluator); ok {
+		root, err := s.Copy()
+		if err != nil {
+			return nil, err
+		}
+		root.Board.SetAccumulator(ie.NewAccumulator())
+		s = root
+	}
+
+	depth := max(opts.Depth, 1)
+	limits := &searchLimits{maxNodes: int64(opts.Nodes), stop: opts.Stop}
+	if opts.MoveTime > 0 {
+		expired := make(chan struct{})
+		timer := time.AfterFunc(opts.MoveTime, func() { close(expired) })
+		defer timer.Stop()
+		limits.expired = expired
+	}
+	if opts.Nodes <= 0 && opts.Stop == nil && opts.MoveTime <= 0 && opts.Info == nil {
+		return searchRoot(s, evaluator, opts, exclude, depth, limits)
+	}
+
+	start := time.Now()
+	var best *SearchResult
+	var stats SearchStats // of every finished iteration, Nodes also counts the aborted one
+	for d := 1; d <= depth; d++ {
+		limits.enforced = d > 1
+		result, err := searchRoot(s, evaluator, opts, exclude, d, limits)
+		if errors.Is(err, errAborted) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats.add(result.SearchStats)
+		best = result
+		if opts.Info != nil {
+			info := *result
+			info.Nodes = int(limits.nodes.Load())
+			info.SearchStats = stats
+			info.Time = time.Since(start)
+			info.NPS = nps(info.Nodes, info.Time)
+			opts.Info(&info)
+		}
+	}
+	best.Nodes = int(limits.nodes.Load())
+	best.SearchStats = stats
+	best.Time = time.Since(start)
+	best.NPS = nps(best.Nodes, best.Time)
+	return best, nil
+}
+
+// nps - nodes per second
+func nps(nodes int, elapsed time.Duration) int {
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		return int(float64(nodes) / seconds)
+	}
+	return 0
+}
+
+// rootMove - the outcome of searching a single move at the root
+type rootMove struct {
+	move  *state.Move
+	score float64
+	pv    []*state.Move
+	err   error
+}
+
+// searchRootMove - plays the move on a copy of s and searches the resulting position
+func (sr *searcher) searchRootMove(s *state.State, move *state.Move, depth int, max bool) (result rootMove) {
+	result.move = move
+	copyState, err := s.Copy()
+	if err != nil {
+		result.err = err
+		return
+	}
+
+	_, err = copyState.ApplyMove(move)
+	if err != nil {
+		result.err = err
+		return
+	}
+
+	sr.trace.enter(1, traceMove(move), depth-1, math.Inf(-1), math.Inf(1))
+	score, childPV, err := sr.minimax(copyState, depth-1, 1, !max, math.Inf(-1), math.Inf(1), true)
+	sr.trace.leave(1, traceMove(move), score, err)
+	if err != nil {
+		result.err = fmt.Errorf("error evalutating move %v: %w", move.ToAlgebraic(), err)
+		return
+	}
+	result.score = score
+	result.pv = append([]*state.Move{move}, childPV...)
+	return
+}
+
+// searchRootParallel - splits the root moves over a number of goroutines
+// every root move is searched with a full window on its own copy of the state, so without a table the
+// scores (and thus the chosen move) are the same as in a single threaded search
+// with a table the threads share its entries, which thread stores one first changes what the others cut off,
+// so scores and lines may differ from run to run
+func searchRootParallel(s *state.State, moves []*state.Move, depth int, max bool, evaluator Evaluator, opts *SearchOptions, limits *searchLimits) ([]rootMove, []*searcher) {
+	results := make([]rootMove, len(moves))
+	searchers := make([]*searcher, opts.Threads)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := range searchers {
+		searchers[i] = newSearcher(evaluator, opts, s.Turn, limits)
+		wg.Add(1)
+		go func(sr *searcher) {
+			defer wg.Done()
+			for index := range indices {
+				results[index] = sr.searchRootMove(s, moves[index], depth, max)
+			}
+		}(searchers[i])
+	}
+	for i := range moves {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results, searchers
+}
+
+// searchRoot - the root loop of the search to the given depth, ignoring the moves in exclude
+func searchRoot(s *state.State, evaluator Evaluator, opts *SearchOptions, exclude []*state.Move, depth int, limits *searchLimits) (*SearchResult, error) {
+	start := time.Now()
+
 	// hardcoded for maximizing player being white (for now)
-	var bestMove *state.Move
-	var bestScore float64
+	result := &SearchResult{Depth: depth, pawnValue: PawnValue(evaluator)}
 	max := s.Turn == "white"
 	if max {
-		bestScore = math.Inf(-1)
+		result.Score = math.Inf(-1)
 	} else {
-		bestScore = math.Inf(1)
+		result.Score = math.Inf(1)
 	}
 
 	// iterate through all legal moves this basically does the first layer of minimax because minimax itself doesn't return the move
 	legalMoves, err := s.GetLegalMoves()
 	if err != nil {
-		return nil, bestScore, err
+		return nil, err
 	}
-	for _, move := range legalMoves {
-		copyState, err := s.Copy()
-		if err != nil {
-			return nil, bestScore, err
-		}
+	moves := slices.DeleteFunc(slices.Clone(legalMoves), func(move *state.Move) bool {
+		return slices.ContainsFunc(exclude, func(m *state.Move) bool {
+			res, _ := m.Equal(move)
+			return res
+		})
+	})
 
-		_, err = copyState.ApplyMove(move)
-		if err != nil {
-			return nil, bestScore, err
+	var rootMoves []rootMove
+	var searchers []*searcher
+	if opts.Threads <= 1 || opts.Trace != nil {
+		sr := newSearcher(evaluator, opts, s.Turn, limits)
+		for _, move := range moves {
+			rootMoves = append(rootMoves, sr.searchRootMove(s, move, depth, max))
 		}
+		searchers = []*searcher{sr}
+	} else {
+		rootMoves, searchers = searchRootParallel(s, moves, depth, max, evaluator, opts, limits)
+	}
 
-		score, err := minimax(copyState, depth-1, !max, math.Inf(-1), math.Inf(1), weights)
-		if err != nil {
-			return nil, score, fmt.Errorf("error evalutating move %v", move.ToAlgebraic())
+	// moves are compared in the order they were generated, so ties are broken the same way every time
+	for _, rm := range rootMoves {
+		if rm.err != nil {
+			return nil, rm.err
+		}
+		if (max && rm.score > result.Score) || (!max && rm.score < result.Score) || result.Move == nil {
+			result.Score = rm.score
+			result.Move = rm.move
+			result.PV = rm.pv
 		}
+	}
 
-		if max {
-			if score > bestScore {
-				bestScore = score
-				bestMove = move
-			}
-		} else {
-			if score < bestScore {
-				bestScore = score
-				bestMove = move
-			}
+	result.rootMoves = rootMoves
+	for _, sr := range searchers {
+		result.Nodes += sr.nodes
+		result.SearchStats.add(sr.stats)
+		if sr.selDepth > result.SelDepth {
+			result.SelDepth = sr.selDepth
 		}
 	}
+	result.Time = time.Since(start)
+	result.NPS = nps(result.Nodes, result.Time)
+	if IsMateScore(result.Score) {
+		result.Mate = true
+		result.MateIn = mateIn(result.Score)
+	}
 	//fmt.Printf("selected move %v", bestMove.ToAlgebraic())
-	return bestMove, bestScore, nil
+	return result, nil
 }
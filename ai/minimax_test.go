@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestSearchDeterministic(t *testing.T) {
+	w := DefaultWeights()
+	for _, fen := range selectivePositions[2:] {
+		single, err := Search(mustFEN(t, fen), &w, &SearchOptions{Depth: 3, Threads: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := Search(mustFEN(t, fen), &w, &SearchOptions{Depth: 3, Threads: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Move.ToAlgebraic() != single.Move.ToAlgebraic() || again.Score != single.Score || again.Nodes != single.Nodes {
+			t.Errorf("%v: single threaded searches differ: %v %v (%v nodes) and %v %v (%v nodes)", fen,
+				single.Move.ToAlgebraic(), single.Score, single.Nodes, again.Move.ToAlgebraic(), again.Score, again.Nodes)
+		}
+
+		// every root move is searched on its own, so without a shared table the threads only change who searches what
+		parallel, err := Search(mustFEN(t, fen), &w, &SearchOptions{Depth: 3, Threads: 4, Table: nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parallel.Move.ToAlgebraic() != single.Move.ToAlgebraic() || parallel.Score != single.Score {
+			t.Errorf("%v: 4 threads found %v %v, 1 thread %v %v", fen,
+				parallel.Move.ToAlgebraic(), parallel.Score, single.Move.ToAlgebraic(), single.Score)
+		}
+	}
+}
+
+func TestMateScores(t *testing.T) {
+	w := DefaultWeights()
+	tests := []struct {
+		name   string
+		fen    string
+		depth  int
+		move   string // "" if there is more than one way to mate
+		mateIn int
+	}{
+		{"mate in 1", "7k/8/6K1/8/8/8/8/Q7 w - - 0 1", 3, "A1-A8", 1},
+		{"mate in 2", "6k1/8/5K2/8/8/8/8/Q7 w - - 0 1", 3, "", 2},
+		// deeper searches find longer mates as well, the shortest one still wins
+		{"shortest mate", "6k1/8/5K2/8/8/8/8/Q7 w - - 0 1", 4, "", 2},
+		{"black mates", "8/8/8/8/8/1k6/8/K6q b - - 0 1", 3, "", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := Search(mustFEN(t, test.fen), &w, &SearchOptions{Depth: test.depth, Threads: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Mate || res.MateIn != test.mateIn || (test.move != "" && res.Move.ToAlgebraic() != test.move) {
+				t.Errorf("Expected %v mating in %v, got %v with score %v (mate %v in %v)",
+					test.move, test.mateIn, res.Move.ToAlgebraic(), res.Score, res.Mate, res.MateIn)
+			}
+			plies := 2*max(test.mateIn, -test.mateIn) - 1
+			if score := MateScore - float64(plies); res.Score != score && res.Score != -score {
+				t.Errorf("Expected a score of ±%v, got %v", score, res.Score)
+			}
+		})
+	}
+}
+
+func TestStalemateContempt(t *testing.T) {
+	w := DefaultWeights()
+	// Kh6 stalemates, white dislikes the draw by the contempt
+	s := mustFEN(t, "7k/5Q2/6K1/8/8/8/8/8 w - - 0 1")
+	for _, contempt := range []float64{0, 1.5} {
+		res, err := Search(s, &w, &SearchOptions{Depth: 1, Threads: 1, Contempt: contempt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, rm := range res.rootMoves {
+			if rm.move.ToAlgebraic() == "G6-H6" {
+				found = true
+				if rm.score != -contempt {
+					t.Errorf("Expected the stalemate to score %v with contempt %v, got %v", -contempt, contempt, rm.score)
+				}
+			}
+		}
+		if !found {
+			t.Fatal("Kh6 wasn't searched")
+		}
+		if !res.Mate || res.MateIn != 1 {
+			t.Errorf("Expected the mate to be preferred over the stalemate, got %v %v", res.Move.ToAlgebraic(), res.Score)
+		}
+	}
+}
+
+func TestFinishKQK(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "8/8/8/4k3/8/8/8/4K2Q w - - 0 1")
+	for range 40 {
+		isMate, err := s.IsCheckmate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isMate {
+			return
+		}
+		if isStale, _ := s.IsStalemate(); isStale {
+			t.Fatalf("Stalemated instead of mating: %v", s.FEN())
+		}
+		res, err := Search(s, &w, &SearchOptions{Depth: 4, Threads: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ApplyMove(res.Move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Errorf("Expected K+Q vs K to be mated within 20 moves, got %v", s.FEN())
+}
+
+func TestScoreString(t *testing.T) {
+	// with material alone a pawn up shows as one pawn, whatever a pawn is worth in the weights
+	for _, material := range []float64{1, 2, 3} {
+		w := Weights{Material: material}
+		res, err := Search(mustFEN(t, "4k3/5pp1/8/8/8/6P1/5PP1/4K3 w - - 0 1"), &w, &SearchOptions{Depth: 2, Threads: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ScoreString() != "+1.00" {
+			t.Errorf("Expected +1.00 with material %v, got %v", material, res.ScoreString())
+		}
+	}
+	w := DefaultWeights()
+	res, err := Search(mustFEN(t, "7k/8/6K1/8/8/8/8/Q7 w - - 0 1"), &w, &SearchOptions{Depth: 2, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ScoreString() != "#1" {
+		t.Errorf("Expected #1, got %v", res.ScoreString())
+	}
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"math"
+	"slices"
+
+	"github.com/spunker/chess/state"
+)
+
+// Move ordering
+// alpha-beta cuts off more the earlier the best move is tried, and late move reductions
+// assume the moves worth looking at come first, so captures and promotions go before quiet moves
+
+// isTactical - a capture or a promotion, the moves that are never pruned or reduced
+func isTactical(s *state.State, move *state.Move) bool {
+	if move.Promotion != "" {
+		return true
+	}
+	target, _ := s.Board.GetPiece(&move.To)
+	return target != nil
+}
+
+// moveOrderScore - most valuable victim first, least valuable attacker second, promotions like a queen capture
+func moveOrderScore(s *state.State, move *state.Move) int {
+	score := 0
+	if move.Promotion != "" {
+		score += 90
+	}
+	target, _ := s.Board.GetPiece(&move.To)
+	if target == nil {
+		return score
+	}
+	attacker, _ := s.Board.GetPiece(&move.From)
+	score += 10*target.Worth + 10
+	if attacker != nil {
+		score -= attacker.Worth
+	}
+	return score
+}
+
+// orderMoves - returns the moves sorted for the search, the original slice is left alone (it is the state's cache)
+// the best move of an earlier search (from the transposition table) comes first, the sort is stable so moves
+// of equal worth stay in generation order
+func orderMoves(s *state.State, moves []*state.Move, first *state.Move) []*state.Move {
+	ordered := slices.Clone(moves)
+	scores := make(map[*state.Move]int, len(ordered))
+	for _, move := range ordered {
+		scores[move] = moveOrderScore(s, move)
+		if first != nil && move.From.Equal(first.From) && move.To.Equal(first.To) && move.Promotion == first.Promotion {
+			scores[move] = math.MaxInt32
+		}
+	}
+	slices.SortStableFunc(ordered, func(a, b *state.Move) int {
+		return scores[b] - scores[a]
+	})
+	return ordered
+}
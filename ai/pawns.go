@@ -0,0 +1,279 @@
+package ai
+
+import (
+	"sync"
+
+	"github.com/spunker/chess/state"
+)
+
+// Pawn structure evaluation
+// pawns move rarely, so everything that only depends on where the pawns are is cached by pawn placement
+
+// passedPawnBonus - raw value of a passed pawn by how far it has advanced (0 = own back rank)
+var passedPawnBonus = [8]float64{0, 0.1, 0.15, 0.25, 0.4, 0.65, 1.0, 0}
+
+// freePathFactor - a passed pawn with nothing in front of it on its file is worth this much more
+const freePathFactor = 1.5
+
+// pawnCacheSize - number of pawn structures kept before the cache is cleared
+const pawnCacheSize = 1 << 16
+
+// PawnStructure - the raw pawn structure terms for each color
+// Doubled, Isolated and Backward count pawns, Passed and ConnectedPassers are scaled by rank
+type PawnStructure struct {
+	Doubled          map[string]float64
+	Isolated         map[string]float64
+	Backward         map[string]float64
+	Passed           map[string]float64
+	ConnectedPassers map[string]float64
+}
+
+// pawnKey - pawn placement of both colors, one bit per square
+type pawnKey struct {
+	white uint64
+	black uint64
+}
+
+// pawnEntry - the part of the pawn structure that only depends on the pawns
+// passed pawns are stored so their free path can be checked against the other pieces
+type pawnEntry struct {
+	doubled          map[string]float64
+	isolated         map[string]float64
+	backward         map[string]float64
+	connectedPassers map[string]float64
+	passers          []passer
+}
+
+// passer - a passed pawn, copied out of the board because pieces change when moves are applied
+type passer struct {
+	color string
+	pos   state.Position
+}
+
+// pawnCache - pawn structure hash table, shared between all searches (and goroutines)
+var pawnCache = struct {
+	sync.RWMutex
+	entries map[pawnKey]*pawnEntry
+}{entries: map[pawnKey]*pawnEntry{}}
+
+// getPawnKey - (helperfunction) returns the pawn placement of the state and the pawns themselves
+func getPawnKey(s *state.State) (key pawnKey, pawns []*state.Piece) {
+	for _, piece := range s.Board.GetPieces() {
+		if piece.Type != "pawn" {
+			continue
+		}
+		bit := uint64(1) << (piece.Pos.Y*8 + piece.Pos.X)
+		if piece.Color == "white" {
+			key.white |= bit
+		} else {
+			key.black |= bit
+		}
+		pawns = append(pawns, piece)
+	}
+	return
+}
+
+// relativeRank - rank of a position seen from the given color (0 = own back rank)
+func relativeRank(color string, pos state.Position) int {
+	if color == "white" {
+		return pos.Y
+	}
+	return 7 - pos.Y
+}
+
+// isAhead - checks whether rank y is in front of rank from, seen from color
+func isAhead(color string, y int, from int) bool {
+	if color == "white" {
+		return y > from
+	}
+	return y < from
+}
+
+// forward - direction in which pawns of the given color move
+func forward(color string) int {
+	if color == "white" {
+		return 1
+	}
+	return -1
+}
+
+func opponent(color string) string {
+	if color == "white" {
+		return "black"
+	}
+	return "white"
+}
+
+// analysePawns - computes the pawn-only part of the pawn structure
+func analysePawns(pawns []*state.Piece) *pawnEntry {
+	entry := &pawnEntry{
+		doubled:          map[string]float64{"white": 0, "black": 0},
+		isolated:         map[string]float64{"white": 0, "black": 0},
+		backward:         map[string]float64{"white": 0, "black": 0},
+		connectedPassers: map[string]float64{"white": 0, "black": 0},
+	}
+
+	// pawns per file (with one extra file on each side so neighbours never go out of range)
+	files := map[string]*[10][]*state.Piece{"white": {}, "black": {}}
+	for _, pawn := range pawns {
+		files[pawn.Color][pawn.Pos.X+1] = append(files[pawn.Color][pawn.Pos.X+1], pawn)
+	}
+
+	for _, color := range []string{"white", "black"} {
+		for file := 1; file <= 8; file++ {
+			if n := len(files[color][file]); n > 1 {
+				entry.doubled[color] += float64(n - 1)
+			}
+		}
+	}
+
+	for _, pawn := range pawns {
+		color := pawn.Color
+		own := files[color]
+		enemy := files[opponent(color)]
+		file := pawn.Pos.X + 1
+
+		if len(own[file-1]) == 0 && len(own[file+1]) == 0 {
+			entry.isolated[color]++
+		} else if isBackward(pawn, own, enemy) {
+			entry.backward[color]++
+		}
+
+		if isPassed(pawn, enemy) {
+			entry.passers = append(entry.passers, passer{color: color, pos: pawn.Pos})
+		}
+	}
+
+	// connected passers - passed pawns next to (or defended by) another passed pawn
+	for _, pawn := range entry.passers {
+		for _, other := range entry.passers {
+			if other.color != pawn.color {
+				continue
+			}
+			dx := other.pos.X - pawn.pos.X
+			dy := other.pos.Y - pawn.pos.Y
+			if (dx == 1 || dx == -1) && dy >= -1 && dy <= 1 {
+				entry.connectedPassers[pawn.color] += passedPawnBonus[relativeRank(pawn.color, pawn.pos)]
+				break
+			}
+		}
+	}
+	return entry
+}
+
+// isPassed - no enemy pawn in front of the pawn on its own or the adjacent files
+func isPassed(pawn *state.Piece, enemy *[10][]*state.Piece) bool {
+	file := pawn.Pos.X + 1
+	for f := file - 1; f <= file+1; f++ {
+		for _, other := range enemy[f] {
+			if isAhead(pawn.Color, other.Pos.Y, pawn.Pos.Y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isBackward - all own pawns on the adjacent files are in front of the pawn, and the
+// square in front of it is guarded by an enemy pawn, so it can't safely advance either
+func isBackward(pawn *state.Piece, own *[10][]*state.Piece, enemy *[10][]*state.Piece) bool {
+	file := pawn.Pos.X + 1
+	for _, f := range []int{file - 1, file + 1} {
+		for _, other := range own[f] {
+			if !isAhead(pawn.Color, other.Pos.Y, pawn.Pos.Y) {
+				return false
+			}
+		}
+	}
+	stop := pawn.Pos.Y + forward(pawn.Color)
+	guard := stop + forward(pawn.Color) // enemy pawns guarding the stop square stand one rank further
+	for _, f := range []int{file - 1, file + 1} {
+		for _, other := range enemy[f] {
+			if other.Pos.Y == guard {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasFreePath - checks whether all squares in front of the pawn are empty
+func hasFreePath(board *state.Board, pawn passer) bool {
+	for y := pawn.pos.Y + forward(pawn.color); y >= 0 && y < 8; y += forward(pawn.color) {
+		piece, err := board.GetPiece(&state.Position{X: pawn.pos.X, Y: y})
+		if err != nil || piece != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// getPawnEntry - looks up the pawn structure in the cache, analysing it if it isn't there
+func getPawnEntry(s *state.State) *pawnEntry {
+	key, pawns := getPawnKey(s)
+	pawnCache.RLock()
+	entry, ok := pawnCache.entries[key]
+	pawnCache.RUnlock()
+	if ok {
+		return entry
+	}
+
+	entry = analysePawns(pawns)
+	pawnCache.Lock()
+	if len(pawnCache.entries) >= pawnCacheSize {
+		pawnCache.entries = map[pawnKey]*pawnEntry{}
+	}
+	pawnCache.entries[key] = entry
+	pawnCache.Unlock()
+	return entry
+}
+
+// EvalPawnStructure - returns all pawn structure terms of the state
+// the returned maps are shared with the pawn cache and must not be modified
+func EvalPawnStructure(s *state.State) *PawnStructure {
+	entry := getPawnEntry(s)
+	passed := map[string]float64{"white": 0, "black": 0}
+	for _, pawn := range entry.passers {
+		bonus := passedPawnBonus[relativeRank(pawn.color, pawn.pos)]
+		if hasFreePath(s.Board, pawn) {
+			bonus *= freePathFactor
+		}
+		passed[pawn.color] += bonus
+	}
+	return &PawnStructure{
+		Doubled:          entry.doubled,
+		Isolated:         entry.isolated,
+		Backward:         entry.backward,
+		Passed:           passed,
+		ConnectedPassers: entry.connectedPassers,
+	}
+}
+
+// pawnStructureTerm - a term reading one field of the pawn structure
+// Terms evaluates the pawn structure once for all of them instead of once per term
+type pawnStructureTerm struct {
+	name  string
+	field func(p *PawnStructure) map[string]float64
+}
+
+func (t pawnStructureTerm) Name() string {
+	return t.name
+}
+
+func (t pawnStructureTerm) Score(s *state.State) (map[string]float64, error) {
+	return t.field(EvalPawnStructure(s)), nil
+}
+
+// pawnTerm - (helperfunction) creates a term out of one field of the pawn structure
+func pawnTerm(name string, field func(p *PawnStructure) map[string]float64) Term {
+	return pawnStructureTerm{name: name, field: field}
+}
+
+// pawn structure terms, doubled, isolated and backward pawns are weaknesses so they should get a negative weight
+var (
+	DoubledPawnsTerm     = pawnTerm("doubled pawns", func(p *PawnStructure) map[string]float64 { return p.Doubled })
+	IsolatedPawnsTerm    = pawnTerm("isolated pawns", func(p *PawnStructure) map[string]float64 { return p.Isolated })
+	BackwardPawnsTerm    = pawnTerm("backward pawns", func(p *PawnStructure) map[string]float64 { return p.Backward })
+	PassedPawnsTerm      = pawnTerm("passed pawns", func(p *PawnStructure) map[string]float64 { return p.Passed })
+	ConnectedPassersTerm = pawnTerm("connected passers", func(p *PawnStructure) map[string]float64 { return p.ConnectedPassers })
+)
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+// pawnValues - the raw pawn structure terms of one color: doubled, isolated, backward, passed and connected passers
+type pawnValues [5]float64
+
+func pawnValuesOf(p *PawnStructure, color string) pawnValues {
+	return pawnValues{p.Doubled[color], p.Isolated[color], p.Backward[color], p.Passed[color], p.ConnectedPassers[color]}
+}
+
+func TestPawnStructure(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		white pawnValues
+		black pawnValues
+	}{
+		// c2 is blocked by c3, so only c3 has a free path
+		{"doubled", "4k3/8/8/8/8/2P5/2P5/4K3 w - - 0 1", pawnValues{1, 2, 0, 0.1 + 0.15*freePathFactor, 0}, pawnValues{}},
+		// b3 can't advance past the pawn on a5 and c4 has left it behind
+		{"backward", "4k3/8/8/p7/2P5/1P6/8/4K3 w - - 0 1", pawnValues{0, 0, 1, 0.25 * freePathFactor, 0}, pawnValues{0, 1, 0, 0, 0}},
+		{"connected passers", "k7/8/8/3PP3/8/8/8/K7 w - - 0 1", pawnValues{0, 0, 0, 2 * 0.4 * freePathFactor, 2 * 0.4}, pawnValues{}},
+		{"blocked passer", "4k3/8/4n3/4P3/8/8/8/4K3 w - - 0 1", pawnValues{0, 1, 0, 0.4, 0}, pawnValues{}},
+		{"no passers", "4k3/3p4/8/8/8/8/4P3/4K3 w - - 0 1", pawnValues{0, 1, 0, 0, 0}, pawnValues{0, 1, 0, 0, 0}},
+	}
+	equal := func(a, b pawnValues) bool {
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	for _, test := range tests {
+		p := EvalPawnStructure(mustFEN(t, test.fen))
+		if white, black := pawnValuesOf(p, "white"), pawnValuesOf(p, "black"); !equal(white, test.white) || !equal(black, test.black) {
+			t.Errorf("%v: expected white %v black %v, got white %v black %v", test.name, test.white, test.black, white, black)
+		}
+		// the same structure with the colors swapped
+		p = EvalPawnStructure(mustFEN(t, mirrorFEN(test.fen)))
+		if white, black := pawnValuesOf(p, "white"), pawnValuesOf(p, "black"); !equal(white, test.black) || !equal(black, test.white) {
+			t.Errorf("%v mirrored: expected white %v black %v, got white %v black %v", test.name, test.black, test.white, white, black)
+		}
+	}
+}
+
+func TestPawnCache(t *testing.T) {
+	pawnCache.Lock()
+	pawnCache.entries = map[pawnKey]*pawnEntry{}
+	pawnCache.Unlock()
+	size := func() int {
+		pawnCache.RLock()
+		defer pawnCache.RUnlock()
+		return len(pawnCache.entries)
+	}
+
+	entry := getPawnEntry(mustFEN(t, "4k3/8/8/8/3P4/8/2N5/4K3 w - - 0 1"))
+	if size() != 1 {
+		t.Fatalf("Expected a miss to add the structure, got %v entries", size())
+	}
+	// only the knight moved, the pawns are the same
+	if hit := getPawnEntry(mustFEN(t, "4k3/8/8/8/3P4/5N2/8/4K3 b - - 0 1")); hit != entry || size() != 1 {
+		t.Errorf("Expected a hit for the same pawns, got %v entries", size())
+	}
+	if miss := getPawnEntry(mustFEN(t, "4k3/8/8/3P4/8/8/2N5/4K3 w - - 0 1")); miss == entry || size() != 2 {
+		t.Errorf("Expected a miss for other pawns, got %v entries", size())
+	}
+
+	// the terms of one evaluation share one lookup and still add up to the single terms
+	s := mustFEN(t, "4k3/3p4/8/p7/2P5/1P6/8/4K3 w - - 0 1")
+	w := DefaultWeights()
+	terms := Terms{}
+	expected := 0.0
+	for _, term := range []Term{DoubledPawnsTerm, IsolatedPawnsTerm, BackwardPawnsTerm, PassedPawnsTerm, ConnectedPassersTerm} {
+		score, err := term.Score(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected += w.PassedPawns * (score["white"] - score["black"])
+		terms = append(terms, WeightedTerm{Term: term, Weight: w.PassedPawns})
+	}
+	if score, err := terms.Evaluate(s); err != nil || math.Abs(score-expected) > 1e-9 {
+		t.Errorf("Expected %v, got %v %v", expected, score, err)
+	}
+}
@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"github.com/spunker/chess/state"
+)
+
+// Piece-square tables: a bonus (or penalty) for a piece standing on a square, in pawns
+// every piece has a middlegame and an endgame table, which are blended based on the game phase
+// tables are written from white's point of view with rank 8 on top, black uses them mirrored
+
+var pawnTableMG = [64]float64{
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.50, 0.50, 0.50, 0.50, 0.50, 0.50, 0.50, 0.50,
+	0.10, 0.10, 0.20, 0.30, 0.30, 0.20, 0.10, 0.10,
+	0.05, 0.05, 0.10, 0.25, 0.25, 0.10, 0.05, 0.05,
+	0.00, 0.00, 0.00, 0.20, 0.20, 0.00, 0.00, 0.00,
+	0.05, -0.05, -0.10, 0.00, 0.00, -0.10, -0.05, 0.05,
+	0.05, 0.10, 0.10, -0.20, -0.20, 0.10, 0.10, 0.05,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+}
+
+var pawnTableEG = [64]float64{
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.80, 0.80, 0.80, 0.80, 0.80, 0.80, 0.80, 0.80,
+	0.50, 0.50, 0.50, 0.50, 0.50, 0.50, 0.50, 0.50,
+	0.30, 0.30, 0.30, 0.30, 0.30, 0.30, 0.30, 0.30,
+	0.15, 0.15, 0.15, 0.15, 0.15, 0.15, 0.15, 0.15,
+	0.05, 0.05, 0.05, 0.05, 0.05, 0.05, 0.05, 0.05,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+}
+
+var knightTableMG = [64]float64{
+	-0.50, -0.40, -0.30, -0.30, -0.30, -0.30, -0.40, -0.50,
+	-0.40, -0.20, 0.00, 0.00, 0.00, 0.00, -0.20, -0.40,
+	-0.30, 0.00, 0.10, 0.15, 0.15, 0.10, 0.00, -0.30,
+	-0.30, 0.05, 0.15, 0.20, 0.20, 0.15, 0.05, -0.30,
+	-0.30, 0.00, 0.15, 0.20, 0.20, 0.15, 0.00, -0.30,
+	-0.30, 0.05, 0.10, 0.15, 0.15, 0.10, 0.05, -0.30,
+	-0.40, -0.20, 0.00, 0.05, 0.05, 0.00, -0.20, -0.40,
+	-0.50, -0.40, -0.30, -0.30, -0.30, -0.30, -0.40, -0.50,
+}
+
+var knightTableEG = [64]float64{
+	-0.50, -0.40, -0.30, -0.30, -0.30, -0.30, -0.40, -0.50,
+	-0.40, -0.20, 0.00, 0.00, 0.00, 0.00, -0.20, -0.40,
+	-0.30, 0.00, 0.10, 0.15, 0.15, 0.10, 0.00, -0.30,
+	-0.30, 0.00, 0.15, 0.20, 0.20, 0.15, 0.00, -0.30,
+	-0.30, 0.00, 0.15, 0.20, 0.20, 0.15, 0.00, -0.30,
+	-0.30, 0.00, 0.10, 0.15, 0.15, 0.10, 0.00, -0.30,
+	-0.40, -0.20, 0.00, 0.00, 0.00, 0.00, -0.20, -0.40,
+	-0.50, -0.40, -0.30, -0.30, -0.30, -0.30, -0.40, -0.50,
+}
+
+var bishopTableMG = [64]float64{
+	-0.20, -0.10, -0.10, -0.10, -0.10, -0.10, -0.10, -0.20,
+	-0.10, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.10,
+	-0.10, 0.00, 0.05, 0.10, 0.10, 0.05, 0.00, -0.10,
+	-0.10, 0.05, 0.05, 0.10, 0.10, 0.05, 0.05, -0.10,
+	-0.10, 0.00, 0.10, 0.10, 0.10, 0.10, 0.00, -0.10,
+	-0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, -0.10,
+	-0.10, 0.05, 0.00, 0.00, 0.00, 0.00, 0.05, -0.10,
+	-0.20, -0.10, -0.10, -0.10, -0.10, -0.10, -0.10, -0.20,
+}
+
+var bishopTableEG = [64]float64{
+	-0.20, -0.10, -0.10, -0.10, -0.10, -0.10, -0.10, -0.20,
+	-0.10, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.10,
+	-0.10, 0.00, 0.05, 0.10, 0.10, 0.05, 0.00, -0.10,
+	-0.10, 0.00, 0.10, 0.15, 0.15, 0.10, 0.00, -0.10,
+	-0.10, 0.00, 0.10, 0.15, 0.15, 0.10, 0.00, -0.10,
+	-0.10, 0.00, 0.05, 0.10, 0.10, 0.05, 0.00, -0.10,
+	-0.10, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.10,
+	-0.20, -0.10, -0.10, -0.10, -0.10, -0.10, -0.10, -0.20,
+}
+
+var rookTableMG = [64]float64{
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.05, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.05,
+	-0.05, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.05,
+	-0.05, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.05,
+	-0.05, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.05,
+	-0.05, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.05,
+	-0.05, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.05,
+	0.00, 0.00, 0.00, 0.05, 0.05, 0.00, 0.00, 0.00,
+}
+
+var rookTableEG = [64]float64{
+	0.05, 0.05, 0.05, 0.05, 0.05, 0.05, 0.05, 0.05,
+	0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+	0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00,
+}
+
+var queenTableMG = [64]float64{
+	-0.20, -0.10, -0.10, -0.05, -0.05, -0.10, -0.10, -0.20,
+	-0.10, 0.00, 0.00, 0.00, 0.00, 0.00, 0.00, -0.10,
+	-0.10, 0.00, 0.05, 0.05, 0.05, 0.05, 0.00, -0.10,
+	-0.05, 0.00, 0.05, 0.05, 0.05, 0.05, 0.00, -0.05,
+	0.00, 0.00, 0.05, 0.05, 0.05, 0.05, 0.00, -0.05,
+	-0.10, 0.05, 0.05, 0.05, 0.05, 0.05, 0.00, -0.10,
+	-0.10, 0.00, 0.05, 0.00, 0.00, 0.00, 0.00, -0.10,
+	-0.20, -0.10, -0.10, -0.05, -0.05, -0.10, -0.10, -0.20,
+}
+
+var queenTableEG = [64]float64{
+	-0.20, -0.10, -0.10, -0.05, -0.05, -0.10, -0.10, -0.20,
+	-0.10, 0.00, 0.05, 0.05, 0.05, 0.05, 0.00, -0.10,
+	-0.10, 0.05, 0.10, 0.10, 0.10, 0.10, 0.05, -0.10,
+	-0.05, 0.05, 0.10, 0.15, 0.15, 0.10, 0.05, -0.05,
+	-0.05, 0.05, 0.10, 0.15, 0.15, 0.10, 0.05, -0.05,
+	-0.10, 0.05, 0.10, 0.10, 0.10, 0.10, 0.05, -0.10,
+	-0.10, 0.00, 0.05, 0.05, 0.05, 0.05, 0.00, -0.10,
+	-0.20, -0.10, -0.10, -0.05, -0.05, -0.10, -0.10, -0.20,
+}
+
+// the king hides behind its pawns in the middlegame...
+var kingTableMG = [64]float64{
+	-0.30, -0.40, -0.40, -0.50, -0.50, -0.40, -0.40, -0.30,
+	-0.30, -0.40, -0.40, -0.50, -0.50, -0.40, -0.40, -0.30,
+	-0.30, -0.40, -0.40, -0.50, -0.50, -0.40, -0.40, -0.30,
+	-0.30, -0.40, -0.40, -0.50, -0.50, -0.40, -0.40, -0.30,
+	-0.20, -0.30, -0.30, -0.40, -0.40, -0.30, -0.30, -0.20,
+	-0.10, -0.20, -0.20, -0.20, -0.20, -0.20, -0.20, -0.10,
+	0.20, 0.20, 0.00, 0.00, 0.00, 0.00, 0.20, 0.20,
+	0.20, 0.30, 0.10, 0.00, 0.00, 0.10, 0.30, 0.20,
+}
+
+// ...and walks to the centre in the endgame
+var kingTableEG = [64]float64{
+	-0.50, -0.40, -0.30, -0.20, -0.20, -0.30, -0.40, -0.50,
+	-0.30, -0.20, -0.10, 0.00, 0.00, -0.10, -0.20, -0.30,
+	-0.30, -0.10, 0.20, 0.30, 0.30, 0.20, -0.10, -0.30,
+	-0.30, -0.10, 0.30, 0.40, 0.40, 0.30, -0.10, -0.30,
+	-0.30, -0.10, 0.30, 0.40, 0.40, 0.30, -0.10, -0.30,
+	-0.30, -0.10, 0.20, 0.30, 0.30, 0.20, -0.10, -0.30,
+	-0.30, -0.30, 0.00, 0.00, 0.00, 0.00, -0.30, -0.30,
+	-0.50, -0.30, -0.30, -0.30, -0.30, -0.30, -0.30, -0.50,
+}
+
+// pieceSquareTables - middlegame and endgame table for each piece type
+var pieceSquareTables = map[string][2]*[64]float64{
+	"pawn":   {&pawnTableMG, &pawnTableEG},
+	"knight": {&knightTableMG, &knightTableEG},
+	"bishop": {&bishopTableMG, &bishopTableEG},
+	"rook":   {&rookTableMG, &rookTableEG},
+	"queen":  {&queenTableMG, &queenTableEG},
+	"king":   {&kingTableMG, &kingTableEG},
+}
+
+// phaseWorth - how much each piece type counts towards the game phase, pawns and kings don't count
+var phaseWorth = map[string]int{
+	"knight": 1,
+	"bishop": 1,
+	"rook":   2,
+	"queen":  4,
+}
+
+// maxPhase - phase of the starting position (4 minor pieces, 4 rooks and 2 queens)
+const maxPhase = 24
+
+// GamePhase - returns how far the game is from the endgame, from 1 (all pieces on the board) to 0 (only pawns and kings)
+func GamePhase(s *state.State) float64 {
+	phase := 0
+	for _, piece := range s.Board.GetPieces() {
+		phase += phaseWorth[piece.Type]
+	}
+	return float64(min(phase, maxPhase)) / maxPhase
+}
+
+// squareIndex - index into a piece-square table for a piece of the given color on pos
+func squareIndex(color string, pos state.Position) int {
+	if color == "white" {
+		return (7-pos.Y)*8 + pos.X
+	}
+	return pos.Y*8 + pos.X
+}
+
+// pieceSquareValue - middlegame and endgame table value of a piece on pos
+func pieceSquareValue(piece *state.Piece, pos state.Position) (mg float64, eg float64) {
+	tables, ok := pieceSquareTables[piece.Type]
+	if !ok {
+		return 0, 0
+	}
+	index := squareIndex(piece.Color, pos)
+	return tables[0][index], tables[1][index]
+}
+
+// getPieceSquareStat - (helperfunction) returns the tapered piece-square score for each color
+func getPieceSquareStat(s *state.State) map[string]float64 {
+	mg := map[string]float64{"white": 0, "black": 0}
+	eg := map[string]float64{"white": 0, "black": 0}
+	for _, piece := range s.Board.GetPieces() {
+		pieceMG, pieceEG := pieceSquareValue(piece, piece.Pos)
+		mg[piece.Color] += pieceMG
+		eg[piece.Color] += pieceEG
+	}
+	phase := GamePhase(s)
+	return map[string]float64{
+		"white": phase*mg["white"] + (1-phase)*eg["white"],
+		"black": phase*mg["black"] + (1-phase)*eg["black"],
+	}
+}
+
+// PieceSquareTerm - how well the pieces of each color are placed, blended between middlegame and endgame
+var PieceSquareTerm Term = TermFunc{
+	TermName: "piece-square",
+	Func: func(s *state.State) (map[string]float64, error) {
+		acc := getAccumulator(s)
+		if acc == nil {
+			return getPieceSquareStat(s), nil
+		}
+		if DebugIncremental {
+			if err := checkIncremental("piece-square", acc.pieceSquareStat(), getPieceSquareStat(s)); err != nil {
+				return nil, err
+			}
+		}
+		return acc.pieceSquareStat(), nil
+	},
+}
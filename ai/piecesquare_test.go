@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+// mirrorFEN - the position with the colors swapped and the board flipped, so black stands where white stood
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swap := func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}
+	turn := "w"
+	if fields[1] == "w" {
+		turn = "b"
+	}
+	return strings.Map(swap, strings.Join(ranks, "/")) + " " + turn + " - - 0 1"
+}
+
+func TestPieceSquareSymmetric(t *testing.T) {
+	for _, fen := range []string{
+		state.StartFEN,
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1",
+		"6k1/5ppp/8/3r4/8/2N5/5PPP/3R2K1 w - - 0 1",
+		"8/5k2/8/2P5/8/8/1K6/8 w - - 0 1",
+	} {
+		score := getPieceSquareStat(mustFEN(t, fen))
+		mirrored := getPieceSquareStat(mustFEN(t, mirrorFEN(fen)))
+		if math.Abs(score["white"]-mirrored["black"]) > 1e-9 || math.Abs(score["black"]-mirrored["white"]) > 1e-9 {
+			t.Errorf("%v: expected mirrored scores, got %v and %v", fen, score, mirrored)
+		}
+	}
+}
+
+func TestGamePhase(t *testing.T) {
+	tests := []struct {
+		fen   string
+		phase float64
+	}{
+		{state.StartFEN, 1},
+		{"4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1", 0},
+		{"3qk3/8/8/8/8/8/8/3QK3 w - - 0 1", 8.0 / 24},
+		{"r3k3/8/8/8/8/8/8/1N2K3 w - - 0 1", 3.0 / 24},
+	}
+	for _, test := range tests {
+		s := mustFEN(t, test.fen)
+		phase := GamePhase(s)
+		if math.Abs(phase-test.phase) > 1e-9 {
+			t.Errorf("%v: expected phase %v, got %v", test.fen, test.phase, phase)
+		}
+
+		// the score is the middlegame tables at phase 1, the endgame tables at phase 0 and blended in between
+		mg := map[string]float64{}
+		eg := map[string]float64{}
+		for _, piece := range s.Board.GetPieces() {
+			pieceMG, pieceEG := pieceSquareValue(piece, piece.Pos)
+			mg[piece.Color] += pieceMG
+			eg[piece.Color] += pieceEG
+		}
+		score := getPieceSquareStat(s)
+		for _, color := range []string{"white", "black"} {
+			expected := test.phase*mg[color] + (1-test.phase)*eg[color]
+			if math.Abs(score[color]-expected) > 1e-9 {
+				t.Errorf("%v: expected %v for %v, got %v", test.fen, expected, color, score[color])
+			}
+		}
+	}
+
+}
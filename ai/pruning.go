@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"math"
+
+	"github.com/spunker/chess/state"
+)
+
+// Selective search
+// plain alpha-beta looks at every move to the full depth, these cut the tree down where
+// a move (or even passing) clearly doesn't matter
+
+// nullMoveReduction - how much shallower the position after a null move is searched
+const nullMoveReduction = 2
+
+// nullMoveMinDepth - null moves are only tried with at least this much depth left, closer to the
+// leaves the reduced search would only be the static evaluation
+const nullMoveMinDepth = 2
+
+// lmrMinDepth - late move reductions are only used with at least this much depth left
+const lmrMinDepth = 3
+
+// lmrFullMoves - number of moves (in search order) that are never reduced
+const lmrFullMoves = 3
+
+// futilityMargins - per remaining depth, how far the static evaluation may be outside the window before
+// quiet moves are skipped, in pawns (the search scales them by the pawn value of its evaluator)
+var futilityMargins = []float64{0, 1.5, 3.5}
+
+// hasPieces - checks whether the color has anything besides its king and pawns
+// without pieces zugzwang is common and passing would be the best move, so null moves are unsafe
+func hasPieces(s *state.State, color string) bool {
+	for _, piece := range s.Board.GetPieces() {
+		if piece.Color == color && piece.Type != "king" && piece.Type != "pawn" {
+			return true
+		}
+	}
+	return false
+}
+
+// nullMoveCutoff - lets the side to move pass and searches the reply with a reduced depth and a null window
+// if the position is still good enough to cause a cutoff, a real move will be too
+func (sr *searcher) nullMoveCutoff(s *state.State, depth int, ply int, max bool, alpha float64, beta float64) (float64, bool, error) {
+	if depth < nullMoveMinDepth || !hasPieces(s, s.Turn) {
+		return 0, false, nil
+	}
+	// with an open window on the side that would be cut there is nothing to prove
+	if (max && math.IsInf(beta, 1)) || (!max && math.IsInf(alpha, -1)) {
+		return 0, false, nil
+	}
+
+	nullDepth := depth - 1 - nullMoveReduction
+	if nullDepth < 0 {
+		nullDepth = 0
+	}
+	next, err := s.NullMove()
+	if err != nil {
+		return 0, false, err
+	}
+	if max {
+		sr.trace.enter(ply+1, traceMove(nil), nullDepth, math.Nextafter(beta, math.Inf(-1)), beta)
+		score, _, err := sr.minimax(next, nullDepth, ply+1, false, math.Nextafter(beta, math.Inf(-1)), beta, false)
+		sr.trace.leave(ply+1, traceMove(nil), score, err)
+		if err != nil || score < beta || IsMateScore(score) {
+			return 0, false, err
+		}
+		return beta, true, nil
+	}
+	sr.trace.enter(ply+1, traceMove(nil), nullDepth, alpha, math.Nextafter(alpha, math.Inf(1)))
+	score, _, err := sr.minimax(next, nullDepth, ply+1, true, alpha, math.Nextafter(alpha, math.Inf(1)), false)
+	sr.trace.leave(ply+1, traceMove(nil), score, err)
+	if err != nil || score > alpha || IsMateScore(score) {
+		return 0, false, err
+	}
+	return alpha, true, nil
+}
+
+// isFutile - checks whether the static evaluation is so far below alpha (above beta for the minimizing side)
+// that no quiet move is expected to bring it back into the window
+func (sr *searcher) isFutile(s *state.State, depth int, max bool, alpha float64, beta float64) (bool, error) {
+	// never prune against an open window or when a mate is at stake, a quiet move might be the only one that avoids it
+	bound := alpha
+	if !max {
+		bound = beta
+	}
+	if IsMateScore(bound) {
+		return false, nil
+	}
+	eval, err := sr.evaluator.Evaluate(s)
+	if err != nil {
+		return false, err
+	}
+	margin := futilityMargins[depth] * sr.pawnValue
+	if max {
+		return eval+margin <= alpha, nil
+	}
+	return eval-margin >= beta, nil
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/spunker/chess/state"
+)
+
+// quiesce - keeps searching captures and promotions once the depth ran out, so a position isn't
+// evaluated in the middle of an exchange (the horizon effect)
+// the side to move may always "stand pat": decline every capture and take the static evaluation
+func (sr *searcher) quiesce(s *state.State, qply int, ply int, max bool, alpha float64, beta float64) (float64, []*state.Move, error) {
+	standPat, err := sr.evaluator.Evaluate(s)
+	if err != nil {
+		return 0, nil, err
+	}
+	if qply >= sr.qDepth {
+		return standPat, nil, nil
+	}
+	if max {
+		if standPat >= beta {
+			return standPat, nil, nil
+		}
+		alpha = math.Max(alpha, standPat)
+	} else {
+		if standPat <= alpha {
+			return standPat, nil, nil
+		}
+		beta = math.Min(beta, standPat)
+	}
+
+	evaln := standPat
+	var pv []*state.Move
+	legalMoves, err := s.GetLegalMoves()
+	if err != nil {
+		return 0, nil, err
+	}
+	searched := 0
+	for _, move := range orderMoves(s, legalMoves, nil) {
+		if !isTactical(s, move) {
+			break // ordered, so only quiet moves are left
+		}
+		copyState, err := s.Copy()
+		if err != nil {
+			return evaln, nil, err
+		}
+		if _, err = copyState.ApplyMove(move); err != nil {
+			return evaln, nil, err
+		}
+		searched++
+
+		sr.trace.enter(ply+1, traceMove(move), 0, alpha, beta)
+		currentEvaln, childPV, err := sr.qnode(copyState, qply+1, ply+1, !max, alpha, beta)
+		sr.trace.leave(ply+1, traceMove(move), currentEvaln, err)
+		if err != nil {
+			return currentEvaln, nil, fmt.Errorf("error evalutating %v: %w", move.ToAlgebraic(), err)
+		}
+
+		if max {
+			if currentEvaln > evaln {
+				evaln = currentEvaln
+				pv = append([]*state.Move{move}, childPV...)
+			}
+			alpha = math.Max(alpha, evaln)
+		} else {
+			if currentEvaln < evaln {
+				evaln = currentEvaln
+				pv = append([]*state.Move{move}, childPV...)
+			}
+			beta = math.Min(beta, evaln)
+		}
+		if beta <= alpha {
+			sr.stats.BetaCutoffs++
+			if searched == 1 {
+				sr.stats.FirstMoveCutoffs++
+			}
+			break
+		}
+	}
+	return evaln, pv, nil
+}
+
+// qnode - a node of the quiescence search, counted and checked for mate like any other node
+func (sr *searcher) qnode(s *state.State, qply int, ply int, max bool, alpha float64, beta float64) (float64, []*state.Move, error) {
+	sr.nodes++
+	sr.stats.QNodes++
+	if ply > sr.selDepth {
+		sr.selDepth = ply
+	}
+	if !sr.limits.visit() {
+		return 0, nil, errAborted
+	}
+
+	isOver, err := s.IsGameOver()
+	if err != nil {
+		return 0, nil, err
+	}
+	if isOver || s.IsInsufficientMaterial() {
+		eval, err := sr.terminalScore(s, ply)
+		return eval, nil, err
+	}
+	return sr.quiesce(s, qply, ply, max, alpha, beta)
+}
@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestQuiescenceHorizon(t *testing.T) {
+	w := DefaultWeights()
+	// the pawn on d5 is defended, taking it with the queen loses the queen one ply past the depth
+	s := mustFEN(t, "4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1")
+	blind, err := Search(s, &w, &SearchOptions{Depth: 1, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blind.Move.ToAlgebraic() != "D1-D5" {
+		t.Fatalf("Expected the search without quiescence to grab the pawn, got %v", blind.Move.ToAlgebraic())
+	}
+	res, err := Search(s, &w, &SearchOptions{Depth: 1, Threads: 1, Quiescence: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Move.ToAlgebraic() == "D1-D5" {
+		t.Errorf("Expected quiescence to see the recapture, got %v with %v", res.Move.ToAlgebraic(), res.Score)
+	}
+	if res.Score >= blind.Score {
+		t.Errorf("Expected a lower score once the recapture is seen, got %v (was %v)", res.Score, blind.Score)
+	}
+}
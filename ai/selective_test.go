@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+// selectivePositions - a fixed set of positions to compare the node counts of the selective search on
+var selectivePositions = []string{
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1",
+	"r3k2r/ppp2ppp/2n1bn2/3pP3/3P4/2N2N2/PPP2PPP/R3KB1R w KQkq - 0 1",
+	"6k1/5ppp/8/3r4/8/2N5/5PPP/3R2K1 w - - 0 1",
+	"4k3/8/3K4/3P4/8/8/8/8 w - - 0 1",
+}
+
+// selectiveConfigs - plain alpha-beta, every technique on its own and all of them together
+var selectiveConfigs = []struct {
+	name string
+	opts SearchOptions
+}{
+	{"plain", SearchOptions{Depth: 4, NoNullMove: true, NoLMR: true, NoFutility: true}},
+	{"nullmove", SearchOptions{Depth: 4, NoLMR: true, NoFutility: true}},
+	{"lmr", SearchOptions{Depth: 4, NoNullMove: true, NoFutility: true}},
+	{"futility", SearchOptions{Depth: 4, NoNullMove: true, NoLMR: true}},
+	{"all", SearchOptions{Depth: 4}},
+}
+
+func TestSelectiveSearchNodes(t *testing.T) {
+	w := DefaultWeights()
+	fen := selectivePositions[2]
+	nodes := map[string]int{}
+	for _, config := range selectiveConfigs {
+		res, err := Search(mustFEN(t, fen), &w, &config.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if move := res.Move.ToAlgebraic(); move != "D1-D5" {
+			t.Errorf("%v: expected D1-D5, got %v", config.name, move)
+		}
+		nodes[config.name] = res.Nodes
+	}
+	for name, n := range nodes {
+		if n > nodes["plain"] {
+			t.Errorf("%v searched more nodes than plain alpha-beta: %v > %v", name, n, nodes["plain"])
+		}
+	}
+}
+
+// BenchmarkSelectiveSearch - node counts per technique on the position set, run with
+// go test ./ai -run ^$ -bench SelectiveSearch -benchtime 1x
+func BenchmarkSelectiveSearch(b *testing.B) {
+	w := DefaultWeights()
+	for _, config := range selectiveConfigs {
+		b.Run(config.name, func(b *testing.B) {
+			for range b.N {
+				nodes := 0
+				for _, fen := range selectivePositions {
+					s, err := state.CreateStateFEN(fen)
+					if err != nil {
+						b.Fatal(err)
+					}
+					res, err := Search(s, &w, &config.opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+					nodes += res.Nodes
+				}
+				b.ReportMetric(float64(nodes), "nodes/op")
+			}
+		})
+	}
+}
+
+func TestFutilityMarginsScale(t *testing.T) {
+	// the same weights at twice the scale must prune the same moves and find the same move
+	w := DefaultWeights()
+	doubled := w
+	for _, param := range doubled.params() {
+		*param.value *= 2
+	}
+	s := selectivePositions[2]
+	opts := SearchOptions{Depth: 4, NoNullMove: true, NoLMR: true}
+	res, err := Search(mustFEN(t, s), &w, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaled, err := Search(mustFEN(t, s), &doubled, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scaled.Nodes != res.Nodes || scaled.Move.ToAlgebraic() != res.Move.ToAlgebraic() {
+		t.Errorf("Expected %v in %v nodes at twice the scale, got %v in %v nodes",
+			res.Move.ToAlgebraic(), res.Nodes, scaled.Move.ToAlgebraic(), scaled.Nodes)
+	}
+}
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/spunker/chess/state"
+)
+
+// MaxSkill - the highest skill level, never plays a move worse than the best one it found
+const MaxSkill = 10
+
+// SkillLevel - how strong the bot plays
+// a weak level searches shallow with few nodes and then doesn't always play the move it liked best
+type SkillLevel struct {
+	Level         int
+	Depth         int
+	Nodes         int     // node limit of the search, 0 = no limit
+	Margin        float64 // moves at most this many pawns worse than the best are played at random (scaled by the evaluator's pawn value)
+	BlunderChance float64 // chance of playing any move, however bad (but never one that gets mated)
+}
+
+// SkillLevels - every level from 0 to MaxSkill
+var SkillLevels = [MaxSkill + 1]SkillLevel{
+	{Level: 0, Depth: 1, Margin: 3.0, BlunderChance: 0.30},
+	{Level: 1, Depth: 1, Margin: 2.0, BlunderChance: 0.20},
+	{Level: 2, Depth: 2, Nodes: 150, Margin: 1.5, BlunderChance: 0.15},
+	{Level: 3, Depth: 2, Nodes: 300, Margin: 1.0, BlunderChance: 0.10},
+	{Level: 4, Depth: 2, Margin: 0.8, BlunderChance: 0.08},
+	{Level: 5, Depth: 3, Nodes: 1500, Margin: 0.5, BlunderChance: 0.05},
+	{Level: 6, Depth: 3, Nodes: 3000, Margin: 0.3, BlunderChance: 0.03},
+	{Level: 7, Depth: 3, Margin: 0.2, BlunderChance: 0.01},
+	{Level: 8, Depth: 3, Margin: 0.1},
+	{Level: 9, Depth: 3, Margin: 0.05},
+	{Level: 10, Depth: 3},
+}
+
+// GetSkillLevel - returns the settings of a skill level
+func GetSkillLevel(level int) (SkillLevel, error) {
+	if level < 0 || level > MaxSkill {
+		return SkillLevel{}, fmt.Errorf("skill level %v out of range 0-%v", level, MaxSkill)
+	}
+	return SkillLevels[level], nil
+}
+
+// NewSkillRand - the random source used to pick between moves, the same seed plays the same game
+func NewSkillRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// SearchSkill - searches the position and picks one of the root moves the way the skill level plays
+// every root move is searched with a full window, so their scores can be compared without searching again
+// the returned result describes the chosen move, which isn't necessarily the best one
+func SearchSkill(s *state.State, evaluator Evaluator, skill SkillLevel, threads int, rng *rand.Rand) (*SearchResult, error) {
+	result, err := Search(s, evaluator, &SearchOptions{Depth: skill.Depth, Threads: threads, Nodes: skill.Nodes})
+	if err != nil || result.Move == nil {
+		return result, err
+	}
+	skill.Margin *= PawnValue(evaluator)
+	chosen := pickRootMove(result.rootMoves, s.Turn, skill, rng)
+
+	picked := *result
+	picked.Move = chosen.move
+	picked.Score = chosen.score
+	picked.PV = chosen.pv
+	picked.Mate = IsMateScore(chosen.score)
+	picked.MateIn = 0
+	if picked.Mate {
+		picked.MateIn = mateIn(chosen.score)
+	}
+	return &picked, nil
+}
+
+// pickRootMove - chooses between the searched root moves, the margin of the skill level is in score units
+func pickRootMove(moves []rootMove, turn string, skill SkillLevel, rng *rand.Rand) rootMove {
+	// scores are white positive, turn them around so higher is better for the side to move
+	sign := 1.0
+	if turn == "black" {
+		sign = -1.0
+	}
+	best := moves[0]
+	for _, rm := range moves[1:] {
+		if sign*rm.score > sign*best.score {
+			best = rm
+		}
+	}
+
+	pool := []rootMove{}
+	if skill.BlunderChance > 0 && rng.Float64() < skill.BlunderChance {
+		// a deliberate inaccuracy, anything goes as long as it doesn't lose on the spot
+		for _, rm := range moves {
+			if !(IsMateScore(rm.score) && sign*rm.score < 0) {
+				pool = append(pool, rm)
+			}
+		}
+	} else {
+		for _, rm := range moves {
+			if sign*best.score-sign*rm.score <= skill.Margin {
+				pool = append(pool, rm)
+			}
+		}
+	}
+	if len(pool) == 0 {
+		return best
+	}
+	return pool[rng.IntN(len(pool))]
+}
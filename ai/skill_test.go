@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"testing"
+)
+
+func TestSkillSeedReproducible(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "r3k2r/ppp2ppp/8/3pP3/8/8/PPP2PPP/R3K2R w KQkq - 0 1")
+	skill, err := GetSkillLevel(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	play := func(seed uint64) (moves []string) {
+		rng := NewSkillRand(seed)
+		for range 8 {
+			res, err := SearchSkill(s, &w, skill, 1, rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			moves = append(moves, res.Move.ToAlgebraic())
+		}
+		return
+	}
+	first, second := play(42), play(42)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Same seed played %v and %v", first, second)
+		}
+	}
+}
+
+func TestSkillNeverWalksIntoMate(t *testing.T) {
+	w := DefaultWeights()
+	// Nb3 walks into Rd1 mate, a pawn move or Nc2 doesn't
+	s := mustFEN(t, "3r2k1/5ppp/8/8/8/8/6PP/N6K w - - 0 1")
+	skill := SkillLevels[0]
+	skill.Depth = 2
+	skill.BlunderChance = 1
+	rng := NewSkillRand(1)
+	for range 20 {
+		res, err := SearchSkill(s, &w, skill, 1, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Mate && res.Score < 0 {
+			t.Fatalf("Skill level picked %v which gets mated", res.Move.ToAlgebraic())
+		}
+	}
+}
+
+func TestNodeLimit(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "r3k2r/ppp2ppp/8/3pP3/8/8/PPP2PPP/R3K2R w KQkq - 0 1")
+	res, err := Search(s, &w, &SearchOptions{Depth: 4, Threads: 1, Nodes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Move == nil || res.Depth >= 4 {
+		t.Errorf("Expected a move from a shallower depth, got depth %v", res.Depth)
+	}
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/spunker/chess/state"
+)
+
+// SearchStats - counters of what the search did, summed over all threads
+type SearchStats struct {
+	QNodes           int // nodes visited by the quiescence search (part of Nodes)
+	BetaCutoffs      int // nodes where a move refuted the position before all moves were searched
+	FirstMoveCutoffs int // beta cutoffs caused by the first move searched, the higher the better the move ordering
+	NullMoveCutoffs  int // nodes cut off by a null move before any move was searched
+	TTProbes         int // transposition table lookups
+	TTHits           int // lookups that found the position
+	TTCutoffs        int // hits that were deep enough to end the node without searching it
+}
+
+// add - adds the counters of another searcher
+func (st *SearchStats) add(other SearchStats) {
+	st.QNodes += other.QNodes
+	st.BetaCutoffs += other.BetaCutoffs
+	st.FirstMoveCutoffs += other.FirstMoveCutoffs
+	st.NullMoveCutoffs += other.NullMoveCutoffs
+	st.TTProbes += other.TTProbes
+	st.TTHits += other.TTHits
+	st.TTCutoffs += other.TTCutoffs
+}
+
+// FirstMoveCutoffRate - share of the beta cutoffs that happened on the first move
+func (st *SearchStats) FirstMoveCutoffRate() float64 {
+	if st.BetaCutoffs == 0 {
+		return 0
+	}
+	return float64(st.FirstMoveCutoffs) / float64(st.BetaCutoffs)
+}
+
+// TTHitRate - share of the transposition table lookups that found the position
+func (st *SearchStats) TTHitRate() float64 {
+	if st.TTProbes == 0 {
+		return 0
+	}
+	return float64(st.TTHits) / float64(st.TTProbes)
+}
+
+// BranchingFactor - the effective branching factor, the number of moves per ply a tree of
+// the searched depth would need to have as many nodes as were visited
+func (r *SearchResult) BranchingFactor() float64 {
+	if r.Depth <= 0 || r.Nodes <= 1 {
+		return 0
+	}
+	return math.Pow(float64(r.Nodes), 1/float64(r.Depth))
+}
+
+// StatsString - all statistics of the search on a single line
+func (r *SearchResult) StatsString() string {
+	return fmt.Sprintf("depth %v/%v nodes %v qnodes %v cutoffs %v (first move %.0f%%) null cutoffs %v tt hits %v/%v (%.0f%%) ebf %.2f",
+		r.Depth, r.SelDepth, r.Nodes, r.QNodes, r.BetaCutoffs, 100*r.FirstMoveCutoffRate(), r.NullMoveCutoffs,
+		r.TTHits, r.TTProbes, 100*r.TTHitRate(), r.BranchingFactor())
+}
+
+// tracer - writes the search tree to a writer, one line when a node is entered and one when it is left
+type tracer struct {
+	w      io.Writer
+	maxPly int
+}
+
+// enter - logs the move leading to a node at the given ply and the window it is searched with
+func (t *tracer) enter(ply int, move string, depth int, alpha float64, beta float64) {
+	if t == nil || ply > t.maxPly {
+		return
+	}
+	fmt.Fprintf(t.w, "%v%v depth %v window [%v, %v]\n", strings.Repeat("  ", ply-1), move, depth, alpha, beta)
+}
+
+// leave - logs the score a node at the given ply returned with
+func (t *tracer) leave(ply int, move string, score float64, err error) {
+	if t == nil || ply > t.maxPly {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(t.w, "%v%v error %v\n", strings.Repeat("  ", ply-1), move, err)
+		return
+	}
+	fmt.Fprintf(t.w, "%v%v = %v\n", strings.Repeat("  ", ply-1), move, score)
+}
+
+// traceMove - the name of a move in the trace
+func traceMove(move *state.Move) string {
+	if move == nil {
+		return "null"
+	}
+	return move.ToAlgebraic()
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSearchStats(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "6k1/5ppp/8/3r4/8/2N5/5PPP/3R2K1 w - - 0 1")
+	opts := &SearchOptions{Depth: 3, Threads: 1, Quiescence: 4, Table: NewTranspositionTable(1)}
+	res, err := Search(s, &w, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(res.StatsString())
+	if res.QNodes == 0 || res.QNodes >= res.Nodes {
+		t.Errorf("Expected quiescence nodes to be part of the nodes, got %v of %v", res.QNodes, res.Nodes)
+	}
+	if res.BetaCutoffs == 0 || res.FirstMoveCutoffs > res.BetaCutoffs {
+		t.Errorf("Unexpected cutoffs %v (first move %v)", res.BetaCutoffs, res.FirstMoveCutoffs)
+	}
+	if res.TTProbes == 0 || res.TTHits > res.TTProbes {
+		t.Errorf("Unexpected tt hits %v/%v", res.TTHits, res.TTProbes)
+	}
+	if ebf := res.BranchingFactor(); ebf <= 1 {
+		t.Errorf("Expected a branching factor above 1, got %v", ebf)
+	}
+
+	// the second search finds everything in the table
+	again, err := Search(s, &w, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Score != res.Score || again.Move.ToAlgebraic() != res.Move.ToAlgebraic() {
+		t.Errorf("Search with a filled table returned %v %v, expected %v %v",
+			again.Move.ToAlgebraic(), again.Score, res.Move.ToAlgebraic(), res.Score)
+	}
+	if again.Nodes >= res.Nodes || again.TTCutoffs == 0 {
+		t.Errorf("Expected the table to save nodes, got %v (was %v) with %v tt cutoffs", again.Nodes, res.Nodes, again.TTCutoffs)
+	}
+}
+
+func TestSearchTrace(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "4k3/8/3K4/3P4/8/8/8/8 w - - 0 1")
+	var trace bytes.Buffer
+	if _, err := Search(s, &w, &SearchOptions{Depth: 2, Threads: 4, Trace: &trace, TracePly: 1}); err != nil {
+		t.Fatal(err)
+	}
+	moves, err := s.GetLegalMoves()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 2*len(moves) {
+		t.Fatalf("Expected an enter and a leave line per root move, got:\n%v", trace.String())
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, " ") {
+			t.Errorf("Line deeper than the traced ply: %q", line)
+		}
+	}
+}
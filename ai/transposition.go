@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"github.com/spunker/chess/state"
+)
+
+// Transposition table
+// the same position is often reached by different move orders, the table remembers what an
+// earlier search found out about it so it doesn't have to be searched again
+
+// bound - what the stored score says about the real score of the position
+type bound uint8
+
+const (
+	boundExact bound = iota // the score is exact
+	boundLower              // the real score is at least the stored one (the search failed high)
+	boundUpper              // the real score is at most the stored one (the search failed low)
+)
+
+// ttEntry - a single stored position
+type ttEntry struct {
+	key   uint64
+	depth int
+	score float64 // mate scores are stored relative to the position, not to the root
+	bound bound
+	move  *state.Move
+}
+
+// TranspositionTable - a fixed size table of searched positions, safe to share between threads and searches
+type TranspositionTable struct {
+	mu      sync.Mutex
+	entries []ttEntry
+}
+
+// cutoff - checks whether the entry answers a search of the position to depth with the window alpha-beta
+// exact scores always do, a lower bound only if it fails high and an upper bound only if it fails low
+func (entry ttEntry) cutoff(depth int, alpha float64, beta float64) bool {
+	if entry.depth < depth {
+		return false
+	}
+	switch entry.bound {
+	case boundExact:
+		return true
+	case boundLower:
+		return entry.score >= beta
+	case boundUpper:
+		return entry.score <= alpha
+	}
+	return false
+}
+
+// tablePV - the principal variation the table remembers from s, starting with move and at most depth moves long
+// exact entries end the search of a node, the rest of their line is followed through the table
+func (sr *searcher) tablePV(s *state.State, move *state.Move, depth int) []*state.Move {
+	pv := []*state.Move{}
+	for move != nil && len(pv) < depth {
+		legalMoves, err := s.GetLegalMoves()
+		if err != nil {
+			break
+		}
+		index := slices.IndexFunc(legalMoves, func(m *state.Move) bool {
+			res, _ := m.Equal(move)
+			return res
+		})
+		if index < 0 { // a different position with the same key
+			break
+		}
+		next, err := s.Copy()
+		if err != nil {
+			break
+		}
+		if _, err := next.ApplyMove(legalMoves[index]); err != nil {
+			break
+		}
+		pv = append(pv, legalMoves[index])
+		entry, ok := sr.table.probe(positionKey(next)^sr.keySalt, 0)
+		if !ok || entry.bound != boundExact {
+			break
+		}
+		s, move = next, entry.move
+	}
+	return pv
+}
+
+// drawScoreSalt - keeps the entries of searches with different draw scores apart
+// a score that depends on a draw somewhere below the position is only right for the contempt (and root side)
+// it was searched with, and the table is shared between searches for both sides
+func drawScoreSalt(drawScore float64) uint64 {
+	if drawScore == 0 {
+		return 0
+	}
+	// splitmix64 finalizer, spreads the bits of the score over the whole key
+	x := math.Float64bits(drawScore) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// NewTranspositionTable - creates a table using about the given number of megabytes
+func NewTranspositionTable(megabytes int) *TranspositionTable {
+	size := max(megabytes, 1) * 1024 * 1024 / int(unsafe.Sizeof(ttEntry{}))
+	return &TranspositionTable{entries: make([]ttEntry, size)}
+}
+
+// Clear - forgets every stored position (for a new game)
+func (tt *TranspositionTable) Clear() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	clear(tt.entries)
+}
+
+// probe - looks up a position, ply converts stored mate scores back to the distance from the root
+func (tt *TranspositionTable) probe(key uint64, ply int) (ttEntry, bool) {
+	tt.mu.Lock()
+	entry := tt.entries[key%uint64(len(tt.entries))]
+	tt.mu.Unlock()
+	if entry.key != key {
+		return ttEntry{}, false
+	}
+	entry.score = mateFromTT(entry.score, ply)
+	return entry, true
+}
+
+// store - saves a position, a deeper entry of another position is only replaced by a search of at least the same depth
+func (tt *TranspositionTable) store(key uint64, ply int, depth int, score float64, b bound, move *state.Move) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	slot := &tt.entries[key%uint64(len(tt.entries))]
+	if slot.key != key && slot.depth > depth {
+		return
+	}
+	*slot = ttEntry{key: key, depth: depth, score: mateToTT(score, ply), bound: b, move: move}
+}
+
+// mateToTT - a mate in n from the root becomes a mate in n-ply from the position
+func mateToTT(score float64, ply int) float64 {
+	if !IsMateScore(score) {
+		return score
+	}
+	if score > 0 {
+		return score + float64(ply)
+	}
+	return score - float64(ply)
+}
+
+// mateFromTT - the reverse of mateToTT
+func mateFromTT(score float64, ply int) float64 {
+	if !IsMateScore(score) {
+		return score
+	}
+	if score > 0 {
+		return score - float64(ply)
+	}
+	return score + float64(ply)
+}
+
+// zobristTable - random numbers for every piece on every square, xor-ed together they identify a position
+// kings and rooks that have moved get their own numbers because they decide the castling rights
+type zobristTable struct {
+	pieces [2][8][64]uint64
+	black  uint64 // black to move
+}
+
+var zobrist = func() (table zobristTable) {
+	rng := rand.New(rand.NewPCG(0x5eed, 0xc4e55))
+	for color := range table.pieces {
+		for typ := range table.pieces[color] {
+			for square := range table.pieces[color][typ] {
+				table.pieces[color][typ][square] = rng.Uint64()
+			}
+		}
+	}
+	table.black = rng.Uint64()
+	return
+}()
+
+// zobristTypes - index of each piece type in the zobrist table
+var zobristTypes = map[string]int{
+	"pawn":   0,
+	"knight": 1,
+	"bishop": 2,
+	"rook":   3,
+	"queen":  4,
+	"king":   5,
+}
+
+const (
+	zobristMovedRook = 6
+	zobristMovedKing = 7
+)
+
+// positionKey - the zobrist key of a position
+func positionKey(s *state.State) (key uint64) {
+	for y, rank := range s.Board.Grid {
+		for x, piece := range rank {
+			if piece == nil {
+				continue
+			}
+			color := 0
+			if piece.Color == "black" {
+				color = 1
+			}
+			typ := zobristTypes[piece.Type]
+			if piece.HasMoved && piece.Type == "rook" {
+				typ = zobristMovedRook
+			} else if piece.HasMoved && piece.Type == "king" {
+				typ = zobristMovedKing
+			}
+			key ^= zobrist.pieces[color][typ][y*8+x]
+		}
+	}
+	if s.Turn == "black" {
+		key ^= zobrist.black
+	}
+	return
+}
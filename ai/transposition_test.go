@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/spunker/chess/state"
+)
+
+func TestTranspositionMateScores(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	move := &state.Move{From: state.Position{X: 0, Y: 0}, To: state.Position{X: 0, Y: 7}}
+	// a node at ply 3 finds a mate 5 plies from the root, that is 2 plies from the node itself
+	tt.store(1, 3, 4, MateScore-5, boundExact, move)
+	tt.store(2, 3, 4, -(MateScore - 5), boundExact, move)
+	tt.store(3, 3, 4, 1.5, boundExact, move)
+
+	// reached again at ply 1 the mate is 3 plies from the root
+	tests := []struct {
+		key   uint64
+		score float64
+	}{
+		{1, MateScore - 3},
+		{2, -(MateScore - 3)},
+		{3, 1.5},
+	}
+	for _, test := range tests {
+		entry, ok := tt.probe(test.key, 1)
+		if !ok {
+			t.Fatalf("Expected key %v to be found", test.key)
+		}
+		if entry.score != test.score {
+			t.Errorf("Key %v: expected %v, got %v", test.key, test.score, entry.score)
+		}
+	}
+	if _, ok := tt.probe(4, 1); ok {
+		t.Error("Expected an unknown key to be missing")
+	}
+}
+
+func TestTranspositionBounds(t *testing.T) {
+	tests := []struct {
+		bound       bound
+		score       float64
+		depth       int
+		alpha, beta float64
+		cutoff      bool
+	}{
+		{boundExact, 1, 3, 0, 2, true},
+		{boundExact, 1, 4, 0, 2, false}, // not searched deep enough
+		{boundLower, 3, 3, 0, 2, true},  // fails high
+		{boundLower, 1, 3, 0, 2, false}, // at least 1 says nothing inside the window
+		{boundUpper, -1, 3, 0, 2, true}, // fails low
+		{boundUpper, 1, 3, 0, 2, false},
+	}
+	for _, test := range tests {
+		entry := ttEntry{depth: 3, score: test.score, bound: test.bound}
+		if cutoff := entry.cutoff(test.depth, test.alpha, test.beta); cutoff != test.cutoff {
+			t.Errorf("Bound %v score %v depth %v window [%v, %v]: expected cutoff %v", test.bound, test.score, test.depth, test.alpha, test.beta, test.cutoff)
+		}
+	}
+
+	// a shallower search of another position doesn't push out a deeper entry
+	tt := NewTranspositionTable(1)
+	size := uint64(len(tt.entries))
+	tt.store(1, 0, 5, 1, boundExact, nil)
+	tt.store(1+size, 0, 2, 2, boundExact, nil)
+	if entry, ok := tt.probe(1, 0); !ok || entry.score != 1 {
+		t.Errorf("Expected the deeper entry to be kept")
+	}
+}
+
+func TestTranspositionPV(t *testing.T) {
+	w := DefaultWeights()
+	s := mustFEN(t, "6k1/5ppp/8/3r4/8/2N5/5PPP/3R2K1 w - - 0 1")
+	opts := &SearchOptions{Depth: 4, Threads: 1, Table: NewTranspositionTable(1)}
+	first, err := Search(s, &w, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second search ends most nodes on exact hits, their lines come from the table
+	second, err := Search(s, &w, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.PV) != len(first.PV) {
+		t.Errorf("Expected the line %v, got %v", first.PVString(), second.PVString())
+	}
+}
+
+func TestTranspositionContempt(t *testing.T) {
+	if drawScoreSalt(0) != 0 {
+		t.Error("Expected searches without contempt to share their entries")
+	}
+	if drawScoreSalt(0.5) == drawScoreSalt(-0.5) || drawScoreSalt(0.5) == 0 {
+		t.Error("Expected draw scores of both sides to be kept apart")
+	}
+
+	// a table filled by a search for white gives the search for black the same answer as an empty one
+	w := DefaultWeights()
+	white := mustFEN(t, "6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1")
+	black := mustFEN(t, "6k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1")
+	shared := NewTranspositionTable(1)
+	if _, err := Search(white, &w, &SearchOptions{Depth: 3, Threads: 1, Contempt: 0.5, Table: shared}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Search(black, &w, &SearchOptions{Depth: 3, Threads: 1, Contempt: 0.5, Table: shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := Search(black, &w, &SearchOptions{Depth: 3, Threads: 1, Contempt: 0.5, Table: NewTranspositionTable(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Score != fresh.Score {
+		t.Errorf("Expected %v with a shared table, got %v", fresh.Score, res.Score)
+	}
+}
@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/spunker/chess/state"
+)
+
+// Texel tuning
+// the weights are tuned so the evaluation, squashed to a win probability by a sigmoid, predicts the results
+// of the games the positions came from as well as possible
+// every term is linear in its weight, so the raw terms of each position are computed once up front and
+// the tuning itself is just arithmetic, that keeps it fast enough for a laptop
+
+// LabelledPosition - a position together with the result of the game it was taken from
+// Result is 1 for a white win, 0.5 for a draw and 0 for a black win
+type LabelledPosition struct {
+	State  *state.State
+	Result float64
+}
+
+// TuneOptions - settings for Tune
+type TuneOptions struct {
+	Iterations int               // maximum number of passes over all weights
+	Step       float64           // how much a weight is nudged at a time
+	Log        func(line string) // called with progress after every pass, may be nil
+}
+
+// parseResult - parses a game result, either "1-0", "0-1", "1/2-1/2" or a number between 0 and 1
+func parseResult(field string) (float64, error) {
+	field = strings.Trim(field, "\"[];")
+	switch field {
+	case "1-0":
+		return 1, nil
+	case "0-1":
+		return 0, nil
+	case "1/2-1/2", "1/2":
+		return 0.5, nil
+	}
+	result, err := strconv.ParseFloat(field, 64)
+	if err != nil || result < 0 || result > 1 {
+		return 0, fmt.Errorf("unknown result %q", field)
+	}
+	return result, nil
+}
+
+// ReadLabelledPositions - reads one position per line, a FEN followed by the game result
+// (e.g. `<fen> 1-0`, `<fen> c9 "1/2-1/2";` or `<fen> [0.5]`), empty lines and lines starting with # are skipped
+func ReadLabelledPositions(r io.Reader) ([]LabelledPosition, error) {
+	result := []LabelledPosition{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %v: expected a fen and a result", number)
+		}
+		res, err := parseResult(fields[len(fields)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", number, err)
+		}
+		fields = fields[:len(fields)-1]
+		if fields[len(fields)-1] == "c9" { // EPD opcode in front of the result
+			fields = fields[:len(fields)-1]
+		}
+		s, err := state.CreateStateFEN(strings.Join(fields, " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", number, err)
+		}
+		result = append(result, LabelledPosition{State: s, Result: res})
+	}
+	return result, scanner.Err()
+}
+
+// tuningSample - the raw terms (white minus black) of a labelled position, in the order of Weights.params
+type tuningSample struct {
+	features []float64
+	result   float64
+}
+
+// sigmoid - win probability for white given an evaluation, k scales evaluations to probabilities
+func sigmoid(eval float64, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*eval/4))
+}
+
+// tuningError - mean squared error between the predicted and real results
+func tuningError(samples []tuningSample, weights []float64, k float64) float64 {
+	total := 0.0
+	for _, sample := range samples {
+		eval := 0.0
+		for i, feature := range sample.features {
+			eval += weights[i] * feature
+		}
+		diff := sample.result - sigmoid(eval, k)
+		total += diff * diff
+	}
+	return total / float64(len(samples))
+}
+
+// fitK - finds the scaling constant that fits the current weights best
+// a coarse scan over (0, 10], refined twice around the best value found
+func fitK(samples []tuningSample, weights []float64) float64 {
+	best, bestErr := 1.0, math.Inf(1)
+	low, high := 0.1, 10.0
+	for _, step := range []float64{0.1, 0.01, 0.001} {
+		for k := low; k <= high; k += step {
+			if e := tuningError(samples, weights, k); e < bestErr {
+				best, bestErr = k, e
+			}
+		}
+		low, high = math.Max(best-step, step/10), best+step
+	}
+	return best
+}
+
+// Tune - optimizes the weights on the labelled positions by local search, starting from start
+// returns the tuned weights and the error before and after tuning
+func Tune(positions []LabelledPosition, start Weights, opts TuneOptions) (Weights, float64, float64, error) {
+	if len(positions) == 0 {
+		return start, 0, 0, fmt.Errorf("no positions to tune on")
+	}
+	if opts.Step == 0 {
+		opts.Step = 0.05
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = 100
+	}
+
+	tuned := start
+	params := tuned.params()
+
+	samples := make([]tuningSample, len(positions))
+	for i, position := range positions {
+		samples[i] = tuningSample{features: make([]float64, len(params)), result: position.Result}
+		for j, param := range params {
+			score, err := param.term.Score(position.State)
+			if err != nil {
+				return start, 0, 0, err
+			}
+			samples[i].features[j] = score["white"] - score["black"]
+		}
+	}
+
+	weights := make([]float64, len(params))
+	for i, param := range params {
+		weights[i] = *param.value
+	}
+	k := fitK(samples, weights)
+	startErr := tuningError(samples, weights, k)
+	bestErr := startErr
+
+	for iteration := 1; iteration <= opts.Iterations; iteration++ {
+		improved := false
+		for i := range weights {
+			for _, delta := range []float64{opts.Step, -opts.Step} {
+				weights[i] += delta
+				if e := tuningError(samples, weights, k); e < bestErr {
+					bestErr = e
+					improved = true
+					break
+				}
+				weights[i] -= delta
+			}
+		}
+		if opts.Log != nil {
+			opts.Log(fmt.Sprintf("iteration %v: error %.6f (k = %.3f)", iteration, bestErr, k))
+		}
+		if !improved {
+			break
+		}
+	}
+
+	for i, param := range params {
+		*param.value = math.Round(weights[i]*1e4) / 1e4
+	}
+	return tuned, startErr, bestErr, nil
+}
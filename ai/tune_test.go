@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadLabelledPositions(t *testing.T) {
+	input := `# comment
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 1/2-1/2
+4k3/8/8/8/8/8/4P3/4K3 w - - c9 "1-0";
+4k3/4p3/8/8/8/8/8/4K3 w - - [0.0]
+`
+	positions, err := ReadLabelledPositions(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 3 {
+		t.Fatalf("Expected 3 positions, got %d", len(positions))
+	}
+	for i, expected := range []float64{0.5, 1, 0} {
+		if positions[i].Result != expected {
+			t.Errorf("Expected result %v for position %d, got %v", expected, i, positions[i].Result)
+		}
+	}
+
+	if _, err := ReadLabelledPositions(strings.NewReader("4k3/8/8/8/8/8/8/4K3 w - - 0 1 2-0")); err == nil {
+		t.Errorf("Expected an error for an unknown result")
+	}
+}
+
+func TestTuneLowersError(t *testing.T) {
+	positions, err := ReadLabelledPositions(strings.NewReader(`
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKB1R w KQkq - 0 1 0-1
+rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 1-0
+4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 1-0
+4k3/4p3/8/8/8/8/8/4K3 w - - 0 1 0-1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, before, after, err := Tune(positions, DefaultWeights(), TuneOptions{Iterations: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after > before {
+		t.Errorf("Expected tuning not to increase the error, %v -> %v", before, after)
+	}
+}
@@ -0,0 +1,281 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Weight profiles on disk
+// a profile is a versioned file holding the weights (and later other evaluator parameters), either as
+// JSON or as TOML (picked by the file extension), unknown keys are rejected so typos don't go unnoticed
+// weights missing from a file keep their default value, so older profiles don't switch off newer terms
+//
+//	{"version": 1, "weights": {"material": 2, ...}}
+//
+//	version = 1
+//	[weights]
+//	material = 2
+
+// ProfileVersion - version of the on-disk format written by SaveWeights
+const ProfileVersion = 1
+
+// DefaultProfile - name of the built in weights, it doesn't exist on disk
+const DefaultProfile = "default"
+
+// Profile - the on-disk format of a weights file
+type Profile struct {
+	Version int     `json:"version"`
+	Weights Weights `json:"weights"`
+}
+
+// SaveWeights - writes the weights to a profile file, as TOML if the path ends in .toml and as JSON otherwise
+func SaveWeights(path string, w *Weights) error {
+	profile := Profile{Version: ProfileVersion, Weights: *w}
+	var data []byte
+	if isTOML(path) {
+		data = profile.toTOML()
+	} else {
+		var err error
+		data, err = json.MarshalIndent(profile, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadWeights - reads the weights from a profile file written by SaveWeights
+// files holding only the weights object (as written by the first version of the tuner) are accepted too
+func LoadWeights(path string) (*Weights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profile *Profile
+	if isTOML(path) {
+		profile, err = profileFromTOML(data)
+	} else {
+		profile, err = profileFromJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if profile.Version > ProfileVersion {
+		return nil, fmt.Errorf("%v: profile version %v is newer than the supported version %v", path, profile.Version, ProfileVersion)
+	}
+	return &profile.Weights, nil
+}
+
+func isTOML(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".toml")
+}
+
+// decodeStrict - json.Unmarshal that fails on keys the target doesn't know
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func profileFromJSON(data []byte) (*Profile, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	profile := &Profile{Weights: DefaultWeights()}
+	if _, ok := keys["weights"]; !ok {
+		// only the weights, without version
+		if err := decodeStrict(data, &profile.Weights); err != nil {
+			return nil, err
+		}
+		return profile, nil
+	}
+	if err := decodeStrict(data, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// weightKeys - the key of every weight in the on-disk format (the json tags of Weights), in field order
+func weightKeys() []string {
+	result := []string{}
+	t := reflect.TypeOf(Weights{})
+	for i := range t.NumField() {
+		result = append(result, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+	return result
+}
+
+// weightByKey - pointer to the weight with the given key, nil if there is no such weight
+func (w *Weights) weightByKey(key string) *float64 {
+	index := slices.Index(weightKeys(), key)
+	if index < 0 {
+		return nil
+	}
+	return reflect.ValueOf(w).Elem().Field(index).Addr().Interface().(*float64)
+}
+
+// WeightNames - the names of all weights as used in profiles (and engine options)
+func WeightNames() []string {
+	return weightKeys()
+}
+
+// Weight - returns the weight with the given name
+func (w *Weights) Weight(name string) (float64, error) {
+	weight := w.weightByKey(name)
+	if weight == nil {
+		return 0, fmt.Errorf("unknown weight %q", name)
+	}
+	return *weight, nil
+}
+
+// SetWeight - changes the weight with the given name
+func (w *Weights) SetWeight(name string, value float64) error {
+	weight := w.weightByKey(name)
+	if weight == nil {
+		return fmt.Errorf("unknown weight %q", name)
+	}
+	*weight = value
+	return nil
+}
+
+// toTOML - writes the profile as TOML
+func (p *Profile) toTOML() []byte {
+	result := fmt.Sprintf("version = %v\n\n[weights]\n", p.Version)
+	for _, key := range weightKeys() {
+		result += fmt.Sprintf("%v = %v\n", key, *p.Weights.weightByKey(key))
+	}
+	return []byte(result)
+}
+
+// profileFromTOML - reads the small part of TOML profiles use: comments, a version and a [weights] table of numbers
+func profileFromTOML(data []byte) (*Profile, error) {
+	profile := &Profile{Weights: DefaultWeights()}
+	table := ""
+	for number, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.SplitN(line, "#", 2)[0])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if table != "weights" {
+				return nil, fmt.Errorf("line %v: unknown table %q", number+1, table)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %v: expected key = value", number+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case table == "" && key == "version":
+			version, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid version %q", number+1, value)
+			}
+			profile.Version = version
+		case table == "weights":
+			weight := profile.Weights.weightByKey(key)
+			if weight == nil {
+				return nil, fmt.Errorf("line %v: unknown weight %q", number+1, key)
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid value %q for %v", number+1, value, key)
+			}
+			*weight = parsed
+		default:
+			return nil, fmt.Errorf("line %v: unknown key %q", number+1, key)
+		}
+	}
+	return profile, nil
+}
+
+// ProfileDir - directory where named profiles are stored
+func ProfileDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chess-minimax", "profiles"), nil
+}
+
+// ListProfiles - names of all profiles in the profile directory, always starting with the default profile
+func ListProfiles() []string {
+	result := []string{DefaultProfile}
+	dir, err := ProfileDir()
+	if err != nil {
+		return result
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return result
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".json" || ext == ".toml") {
+			result = append(result, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	return result
+}
+
+// ProfilePath - path of the named profile in the profile directory (an existing .json or .toml file, .json otherwise)
+func ProfilePath(name string) (string, error) {
+	dir, err := ProfileDir()
+	if err != nil {
+		return "", err
+	}
+	for _, ext := range []string{".json", ".toml"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// LoadProfile - loads weights by profile name or by path
+// anything that looks like a path (has an extension or a directory) is read as a file
+func LoadProfile(nameOrPath string) (*Weights, error) {
+	if nameOrPath == "" || nameOrPath == DefaultProfile {
+		w := DefaultWeights()
+		return &w, nil
+	}
+	if filepath.Ext(nameOrPath) != "" || strings.ContainsRune(nameOrPath, filepath.Separator) {
+		return LoadWeights(nameOrPath)
+	}
+	path, err := ProfilePath(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("unknown profile %q (looked in %v)", nameOrPath, filepath.Dir(path))
+	}
+	return LoadWeights(path)
+}
+
+// SaveProfile - saves weights as a named profile in the profile directory
+func SaveProfile(name string, w *Weights) (string, error) {
+	if name == DefaultProfile {
+		return "", fmt.Errorf("the %q profile is built in and can't be overwritten", DefaultProfile)
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, SaveWeights(path, w)
+}
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoadWeights(t *testing.T) {
+	w := DefaultWeights()
+	w.KingAttackers = -0.25
+	for _, name := range []string{"weights.json", "weights.toml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveWeights(path, &w); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadWeights(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *loaded != w {
+			t.Errorf("%v: expected %+v, got %+v", name, w, *loaded)
+		}
+	}
+}
+
+func TestLoadWeightsValidation(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"unknown.json": `{"version": 1, "weights": {"material": 2, "materiel": 1}}`,
+		"unknown.toml": "version = 1\n[weights]\nmateriel = 1\n",
+		"table.toml":   "version = 1\n[search]\ndepth = 3\n",
+		"newer.json":   `{"version": 99, "weights": {}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadWeights(path); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+
+	// the plain weights written by the first version of the tuner
+	path := filepath.Join(dir, "plain.json")
+	if err := os.WriteFile(path, []byte(`{"material": 3, "mobility": 0.25}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := LoadWeights(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Material != 3 || w.Mobility != 0.25 {
+		t.Errorf("Expected material 3 and mobility 0.25, got %+v", *w)
+	}
+}
+
+func TestLoadPartialWeights(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"plain.json":   `{"material": 3, "mobility": 0.25}`,
+		"partial.json": `{"version": 1, "weights": {"material": 3, "mobility": 0.25}}`,
+		"partial.toml": "version = 1\n[weights]\nmaterial = 3\nmobility = 0.25\n",
+	}
+	expected := DefaultWeights()
+	expected.Material = 3
+	expected.Mobility = 0.25
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w, err := LoadWeights(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the weights the file doesn't mention keep their defaults instead of turning their terms off
+		if *w != expected {
+			t.Errorf("%v: expected %+v, got %+v", name, expected, *w)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
+	"github.com/spunker/chess/state"
+)
+
+// command - a subcommand of the chess binary
+type command struct {
+	name    string
+	args    string // what follows the flags in the usage line
+	summary string
+	run     func(args []string) error
+}
+
+// commandList - every subcommand, in the order of the help
+func commandList() []command {
+	return []command{
+		{"play", "", "play against the bot in the terminal (the default command)", runPlay},
+		{"analyze", "[fen]", "print the best moves of a position, a FEN or the end of a PGN game", runAnalyze},
+		{"perft", "", "count the move sequences of a given length, to check the move generator", runPerft},
+		{"selfplay", "", "let the bot play against itself and print the games in PGN", runSelfplay},
+		{"match", "", "play a match between two engines and estimate their Elo difference", runMatch},
+		{"tune", "", "tune the evaluation weights on labelled positions", runTune},
+		{"uci", "", "speak UCI on stdin and stdout, for chess GUIs", runUCI},
+		{"xboard", "", "speak the xboard protocol on stdin and stdout, for chess GUIs", runXBoard},
+		{"serve", "", "serve games and analysis over a local HTTP JSON API", runServe},
+		{"multiplayer", "", "host games between two players over WebSockets", runMultiplayer},
+	}
+}
+
+// runCommand - runs the subcommand named by the first argument, play if there is none
+func runCommand(args []string) error {
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !slices.Contains([]string{"-h", "-help", "--help"}, args[0])) {
+		return runPlay(args)
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(args) > 1 && name == "help" {
+			return runCommand([]string{args[1], "-h"})
+		}
+		printCommands(os.Stdout)
+		return nil
+	}
+	for _, c := range commandList() {
+		if c.name == name {
+			return c.run(args[1:])
+		}
+	}
+	printCommands(os.Stderr)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// printCommands - the usage of the binary
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "usage: chess <command> [flags]\n\ncommands:\n")
+	for _, c := range commandList() {
+		fmt.Fprintf(w, "  %-12v %v\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nrun chess <command> --help for the flags of a command\n")
+}
+
+// newFlagSet - the flags of a subcommand, --help prints its usage
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		for _, c := range commandList() {
+			if c.name == name {
+				fmt.Fprintf(flags.Output(), "usage: chess %v [flags] %v\n\n%v\n\nflags:\n", name, c.args, c.summary)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// runPlay - the play command, starts the TUI
+func runPlay(args []string) error {
+	flags := newFlagSet("play")
+	playerColor := flags.String("color", "white", "color you play, white or black")
+	setup := flags.String("setup", "default", "setup of the board: default, castling, promotion or clear")
+	fen := flags.String("fen", "", "position to start from instead of the setup")
+	depth := flags.Int("depth", defaultEngineDepth, "search depth of the bot, at least 1")
+	profile := flags.String("profile", "", "weights profile to play with, a profile name or a path to a .json/.toml file")
+	skill := flags.Int("skill", -1, fmt.Sprintf("skill level of the bot from 0 to %v, -1 plays at full strength", ai.MaxSkill))
+	seed := flags.Uint64("seed", uint64(time.Now().UnixNano()), "seed for the moves picked by a skill level, the same seed plays the same moves")
+	enginePath := flags.String("engine", "", "path to a UCI engine binary to play against instead of the bot")
+	flags.Parse(args)
+
+	if *playerColor != "white" && *playerColor != "black" {
+		return fmt.Errorf("unknown color %q, expected white or black", *playerColor)
+	}
+	if !slices.Contains([]string{"default", "castling", "promotion", "clear"}, *setup) {
+		return fmt.Errorf("unknown setup %q", *setup)
+	}
+	if *fen != "" {
+		if _, err := state.CreateStateFEN(*fen); err != nil {
+			return err
+		}
+	}
+	if *depth < 1 {
+		return fmt.Errorf("depth %v must be at least 1", *depth)
+	}
+	if *skill < -1 || *skill > ai.MaxSkill {
+		return fmt.Errorf("skill level %v out of range -1-%v", *skill, ai.MaxSkill)
+	}
+	StartTui(TuiOptions{
+		Profile:    *profile,
+		Color:      *playerColor,
+		Setup:      *setup,
+		FEN:        *fen,
+		Depth:      *depth,
+		Skill:      *skill,
+		Seed:       *seed,
+		EnginePath: *enginePath,
+	})
+	return nil
+}
+
+// runAnalyze - the analyze command
+func runAnalyze(args []string) error {
+	return analyze(os.Stdout, args)
+}
+
+// analyze - runs the analyze command, printing to out
+func analyze(out io.Writer, args []string) error {
+	flags := newFlagSet("analyze")
+	fen := flags.String("fen", "", "position to analyze (may also be given as the arguments)")
+	pgnPath := flags.String("pgn", "", "PGN file, the position at the end of its first game is analyzed")
+	depth := flags.Int("depth", defaultEngineDepth, "search depth")
+	moveTime := flags.Int("movetime", 0, "stop after this many milliseconds, searching as deep as it gets")
+	lines := flags.Int("lines", 1, "number of best moves to print")
+	profile := flags.String("profile", "", "weights profile to evaluate with, a profile name or a path to a .json/.toml file")
+	threads := flags.Int("threads", 1, "number of search threads")
+	flags.Parse(args)
+
+	var g *game.Game
+	var err error
+	switch {
+	case *pgnPath != "":
+		data, err := os.ReadFile(*pgnPath)
+		if err != nil {
+			return err
+		}
+		g, err = game.ParsePGN(string(data))
+		if err != nil {
+			return fmt.Errorf("%v: %w", *pgnPath, err)
+		}
+	case *fen != "":
+		g, err = game.StartGameFEN(*fen)
+	case flags.NArg() > 0:
+		g, err = game.StartGameFEN(strings.Join(flags.Args(), " "))
+	default:
+		g, err = game.StartGame("default")
+	}
+	if err != nil {
+		return err
+	}
+	weights, err := ai.LoadProfile(*profile)
+	if err != nil {
+		return err
+	}
+
+	opts := &ai.SearchOptions{
+		Depth:      max(*depth, 1),
+		Threads:    max(*threads, 1),
+		Quiescence: defaultQuiescence,
+		Table:      ai.NewTranspositionTable(defaultHash),
+	}
+	if *moveTime > 0 {
+		if !isFlagSet(flags, "depth") {
+			opts.Depth = maxEngineDepth
+		}
+		// every line is a search of its own and gets its share of the time
+		opts.MoveTime = time.Duration(*moveTime) * time.Millisecond / time.Duration(max(*lines, 1))
+	}
+
+	s := g.State
+	fmt.Fprintf(out, "position %v\n", s.FEN())
+	if g.Over {
+		result, reason := g.Result()
+		fmt.Fprintf(out, "game over: %v {%v}\n", result, reason)
+		return nil
+	}
+	if claim := g.Claimable(); claim != "" {
+		fmt.Fprintf(out, "draw by %v can be claimed\n", claim)
+	}
+	results, err := ai.SearchMultiPV(s, weights, opts, max(*lines, 1))
+	if err != nil {
+		return err
+	}
+	for i, result := range results {
+		pv, err := sanLine(s, result.PV)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%v. %v (depth %v, %v nodes) %v\n", i+1, formatScore(result, ai.PawnValue(weights)), result.Depth, result.Nodes, pv)
+	}
+	return nil
+}
+
+// isFlagSet - true if the flag was given on the command line
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// formatScore - the score in pawns from white's side, #n for a mate in n moves (negative when black mates)
+// pawn is what a pawn is worth in the evaluation's units
+func formatScore(result *ai.SearchResult, pawn float64) string {
+	if result.Mate {
+		return fmt.Sprintf("#%v", result.MateIn)
+	}
+	return fmt.Sprintf("%+.2f", result.Score/pawn)
+}
+
+// sanLine - the moves from the position in SAN, the full move number isn't kept by the state so lines start at 1
+func sanLine(s *state.State, moves []*state.Move) (string, error) {
+	tokens, err := game.SANMoves(s, moves)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+// runPerft - the perft command
+func runPerft(args []string) error {
+	flags := newFlagSet("perft")
+	depth := flags.Int("depth", 3, "length of the move sequences")
+	fen := flags.String("fen", state.StartFEN, "position to count from")
+	divide := flags.Bool("divide", false, "print the count of every legal move")
+	flags.Parse(args)
+
+	s, err := state.CreateStateFEN(*fen)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	nodes := 0
+	if *divide && *depth > 0 {
+		legalMoves, err := s.GetLegalMoves()
+		if err != nil {
+			return err
+		}
+		for _, move := range legalMoves {
+			next, err := s.Copy()
+			if err != nil {
+				return err
+			}
+			if _, err := next.ApplyMove(move); err != nil {
+				return err
+			}
+			n, err := next.Perft(*depth - 1)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%v: %v\n", move.UCI(), n)
+			nodes += n
+		}
+		fmt.Println()
+	} else if nodes, err = s.Perft(*depth); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("nodes %v time %v nps %.0f\n", nodes, elapsed.Round(time.Millisecond), float64(nodes)/max(elapsed.Seconds(), 1e-9))
+	return nil
+}
+
+// runSelfplay - the selfplay command
+func runSelfplay(args []string) error {
+	flags := newFlagSet("selfplay")
+	spec := flags.String("player", "depth=2", "the bot playing both sides, like the players of the match command")
+	games := flags.Int("games", 1, "number of games")
+	fen := flags.String("fen", "", "position the games start from (default: the start position)")
+	maxPlies := flags.Int("maxplies", defaultMaxPlies, "games are drawn after this many plies")
+	pgnPath := flags.String("pgn", "", "file the games are written to (default: stdout)")
+	flags.Parse(args)
+
+	out := io.Writer(os.Stdout)
+	if *pgnPath != "" {
+		file, err := os.Create(*pgnPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	bot, err := parsePlayerSpec(*spec)
+	if err != nil {
+		return err
+	}
+	defer bot.player.close()
+
+	for round := 1; round <= *games; round++ {
+		g, err := game.StartPosition(*fen, nil)
+		if err != nil {
+			return err
+		}
+		result, termination, err := playMatchGame(g, bot, bot, *maxPlies)
+		if err != nil {
+			return err
+		}
+		pgn, err := g.PGN(game.PGNHeader{
+			Event:       "selfplay",
+			Site:        engineName,
+			Date:        time.Now(),
+			Round:       strconv.Itoa(round),
+			Result:      result,
+			Termination: termination,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(out, pgn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
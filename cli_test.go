@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestAnalyzeMoveTime(t *testing.T) {
+	var out bytes.Buffer
+	if err := analyze(&out, []string{"-movetime", "900", "-lines", "3", "-threads", "1", "4k3/8/8/8/8/8/3PP3/R3K3 w - - 0 1"}); err != nil {
+		t.Fatal(err)
+	}
+	// the lines after the first get their own time, not a stop that already fired
+	depths := regexp.MustCompile(`(?m)^(\d)\. \S+ \(depth (\d+),`).FindAllStringSubmatch(out.String(), -1)
+	if len(depths) != 3 {
+		t.Fatalf("Expected 3 lines, got:\n%v", out.String())
+	}
+	for _, line := range depths {
+		if depth, _ := strconv.Atoi(line[2]); depth < 2 {
+			t.Errorf("Expected line %v to reach depth 2 or more, got:\n%v", line[1], out.String())
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
+)
+
+// Engine session shared by the text protocols (uci, xboard)
+// it holds the position and the settings the GUI sent, and runs one search at a time in the background
+
+// defaultEngineDepth - depth searched when the GUI gives no limit at all
+const defaultEngineDepth = 3
+
+// maxEngineDepth - depth of a search that only ends by time or by the GUI
+const maxEngineDepth = 64
+
+// defaultHash - size of the transposition table in megabytes
+const defaultHash = 16
+
+// defaultQuiescence - plies of captures searched after the depth runs out
+const defaultQuiescence = 4
+
+// engine - the state of the engine behind a protocol
+type engine struct {
+	game       *game.Game
+	weights    ai.Weights
+	table      *ai.TranspositionTable
+	hash       int
+	threads    int
+	quiescence int
+	invalid    error // why the last position couldn't be set up, searches answer without a move until a new one is set
+
+	mu       sync.Mutex
+	stopFunc func()        // ends the running search, nil when no search was started
+	discard  *atomic.Bool  // set to drop the result of the running search instead of reporting it
+	done     chan struct{} // closed when the running search has reported its move
+}
+
+// newEngine - creates an engine in the starting position
+func newEngine(weights ai.Weights) *engine {
+	g, _ := game.StartGame("default")
+	return &engine{
+		game:       g,
+		weights:    weights,
+		table:      ai.NewTranspositionTable(defaultHash),
+		hash:       defaultHash,
+		threads:    runtime.NumCPU(),
+		quiescence: defaultQuiescence,
+	}
+}
+
+// newGame - forgets everything learned in the previous game
+func (e *engine) newGame() {
+	e.cancelSearch()
+	e.table.Clear()
+	e.game, _ = game.StartGame("default")
+	e.invalid = nil
+}
+
+// setPosition - sets up the position from a fen ("" for the starting position) and plays the moves (coordinate notation)
+// when that fails the session is invalid, searching the previous position would answer with a move for the wrong position
+func (e *engine) setPosition(fen string, moves []string) error {
+	e.cancelSearch()
+	g, err := game.StartPosition(fen, moves)
+	e.invalid = err
+	if err != nil {
+		return err
+	}
+	e.game = g
+	return nil
+}
+
+// setHash - replaces the transposition table with one of the given size in megabytes
+func (e *engine) setHash(megabytes int) {
+	e.cancelSearch()
+	e.hash = max(megabytes, 1)
+	e.table = ai.NewTranspositionTable(e.hash)
+}
+
+// setWeight - changes an evaluation weight, the table is cleared because its scores were made with the old weights
+func (e *engine) setWeight(name string, value float64) error {
+	e.cancelSearch()
+	if err := e.weights.SetWeight(name, value); err != nil {
+		return err
+	}
+	e.table.Clear()
+	return nil
+}
+
+// searchLimits - how long the GUI lets the engine think, zero values are not set
+type searchLimits struct {
+	depth     int
+	nodes     int
+	moveTime  time.Duration
+	wtime     time.Duration
+	btime     time.Duration
+	winc      time.Duration
+	binc      time.Duration
+	movesToGo int
+	infinite  bool
+}
+
+// timeBudget - how long to think on this move, 0 for no time limit
+// a fixed share of the remaining clock plus most of the increment, never more than half of the clock
+func (l searchLimits) timeBudget(turn string) time.Duration {
+	if l.moveTime > 0 {
+		return l.moveTime
+	}
+	remaining, inc := l.wtime, l.winc
+	if turn == "black" {
+		remaining, inc = l.btime, l.binc
+	}
+	if remaining <= 0 {
+		return 0
+	}
+	movesToGo := l.movesToGo
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+	budget := remaining/time.Duration(movesToGo) + inc*3/4
+	return min(budget, remaining/2)
+}
+
+// search - starts searching the current position in the background
+// info is called after every finished depth, done with the final result (also when the search was stopped)
+// with infinite set, done is only called after stopSearch, as the protocols require
+func (e *engine) search(limits searchLimits, info func(*ai.SearchResult), done func(*ai.SearchResult, error)) {
+	e.cancelSearch()
+	if e.invalid != nil {
+		done(nil, fmt.Errorf("no valid position: %w", e.invalid))
+		return
+	}
+	s, err := e.game.State.Copy()
+	if err != nil {
+		done(nil, err)
+		return
+	}
+
+	depth := limits.depth
+	budget := limits.timeBudget(s.Turn)
+	if depth <= 0 {
+		if budget > 0 || limits.infinite || limits.nodes > 0 {
+			depth = maxEngineDepth
+		} else {
+			depth = defaultEngineDepth
+		}
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	stopFunc := func() { once.Do(func() { close(stop) }) }
+	var timer *time.Timer
+	if budget > 0 {
+		timer = time.AfterFunc(budget, stopFunc)
+	}
+	finished := make(chan struct{})
+	discard := &atomic.Bool{}
+	e.mu.Lock()
+	e.stopFunc, e.discard, e.done = stopFunc, discard, finished
+	e.mu.Unlock()
+
+	weights := e.weights
+	opts := &ai.SearchOptions{
+		Depth:      depth,
+		Threads:    e.threads,
+		Nodes:      limits.nodes,
+		Quiescence: e.quiescence,
+		Table:      e.table,
+		Stop:       stop,
+		Info:       info,
+	}
+	go func() {
+		defer close(finished)
+		result, err := ai.Search(s, &weights, opts)
+		if timer != nil {
+			timer.Stop()
+		}
+		if limits.infinite {
+			<-stop
+		}
+		if !discard.Load() {
+			done(result, err)
+		}
+	}()
+}
+
+// stopSearch - ends the running search (if any) and waits until it reported its move
+func (e *engine) stopSearch() {
+	e.endSearch(false)
+}
+
+// cancelSearch - ends the running search (if any) without reporting its move
+// used when the position changes under it, the move wouldn't make sense anymore
+func (e *engine) cancelSearch() {
+	e.endSearch(true)
+}
+
+// endSearch - stops the running search and waits for it
+func (e *engine) endSearch(discard bool) {
+	e.mu.Lock()
+	stop, done := e.stopFunc, e.done
+	if discard && e.discard != nil {
+		e.discard.Store(true)
+	}
+	e.stopFunc, e.discard, e.done = nil, nil, nil
+	e.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+// pawnValue - what a pawn is worth in the scores of the engine's weights
+func (e *engine) pawnValue() float64 {
+	return ai.PawnValue(&e.weights)
+}
+
+// centipawns - the score of a result from the point of view of the side to move
+// scores are in evaluation units, pawn is what a pawn is worth in them
+func centipawns(score, pawn float64, turn string) int {
+	if turn == "black" {
+		score = -score
+	}
+	return int(math.Round(score / pawn * 100))
+}
+
+// engineName - the name the engine reports to GUIs
+const engineName = "chess-minimax-go"
+
+// protocolWriter - writes the lines of a protocol, the search reports from its own goroutine so writes are serialized
+type protocolWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// writeLine - writes a single line
+func (p *protocolWriter) writeLine(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+// protocolHandler - a protocol front end, handle returns false on quit
+type protocolHandler interface {
+	handle(line string) bool
+}
+
+// runProtocol - feeds the input to the handler line by line, until quit or the end of the input
+func runProtocol(in io.Reader, handler protocolHandler, e *engine) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !handler.handle(scanner.Text()) {
+			return nil
+		}
+	}
+	e.stopSearch()
+	return scanner.Err()
+}
+
+// protocolWeights - parses the flags of a protocol command and loads the weights to play with
+func protocolWeights(command string, args []string) (*ai.Weights, error) {
+	flags := newFlagSet(command)
+	profile := flags.String("profile", "", "weights profile to play with, a profile name or a path to a .json/.toml file")
+	flags.Parse(args)
+	return ai.LoadProfile(*profile)
+}
+
+// coordinatePV - the principal variation in coordinate notation
+func coordinatePV(result *ai.SearchResult) string {
+	pv := make([]string, len(result.PV))
+	for i, move := range result.PV {
+		pv[i] = move.UCI()
+	}
+	return strings.Join(pv, " ")
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spunker/chess/state"
+)
+
+// External engines - any UCI engine binary run as a subprocess, playing through the same State as our bot
+
+// externalEngineTimeout - how long to wait for an answer when no time limit says otherwise
+const externalEngineTimeout = time.Minute
+
+// externalEngineGrace - how long an engine may overrun its time before it is told to stop
+const externalEngineGrace = 5 * time.Second
+
+// errEngineExited - the engine process is gone, it can't play any more games
+var errEngineExited = errors.New("engine exited")
+
+// externalEngine - a running UCI engine
+type externalEngine struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string   // the engine's output, closed when it exits
+	done  chan struct{} // closed when it exits
+	name  string        // from "id name", the path if the engine doesn't say
+	grace time.Duration // how long it may overrun its time, see externalEngineGrace
+}
+
+// startExternalEngine - starts the engine binary and does the uci handshake
+func startExternalEngine(path string, args ...string) (*externalEngine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting engine %v: %w", path, err)
+	}
+	e := &externalEngine{cmd: cmd, stdin: stdin, lines: make(chan string, 64), done: make(chan struct{}), name: path, grace: externalEngineGrace}
+	go func() {
+		defer close(e.done)
+		defer close(e.lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			e.lines <- scanner.Text()
+		}
+	}()
+
+	if err := e.send("uci"); err != nil {
+		e.close()
+		return nil, err
+	}
+	for {
+		line, err := e.readLine(externalEngineTimeout)
+		if err != nil {
+			e.close()
+			return nil, fmt.Errorf("engine %v: no uciok: %w", path, err)
+		}
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			e.name = strings.TrimSpace(name)
+		}
+		if line == "uciok" {
+			break
+		}
+	}
+	if err := e.ready(); err != nil {
+		e.close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// send - writes a command to the engine
+func (e *externalEngine) send(format string, args ...any) error {
+	if _, err := fmt.Fprintf(e.stdin, format+"\n", args...); err != nil {
+		select {
+		case <-e.done:
+			return errEngineExited
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// readLine - the next line of the engine's output
+func (e *externalEngine) readLine(timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", errEngineExited
+		}
+		return strings.TrimSpace(line), nil
+	case <-timer.C:
+		return "", fmt.Errorf("no answer after %v", timeout)
+	}
+}
+
+// ready - waits until the engine has handled every command sent so far
+func (e *externalEngine) ready() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	for {
+		line, err := e.readLine(externalEngineTimeout)
+		if err != nil {
+			return fmt.Errorf("engine %v: no readyok: %w", e.name, err)
+		}
+		if line == "readyok" {
+			return nil
+		}
+	}
+}
+
+// setOption - sets a UCI option of the engine
+func (e *externalEngine) setOption(name, value string) error {
+	if err := e.send("setoption name %v value %v", name, value); err != nil {
+		return err
+	}
+	return e.ready()
+}
+
+// newGame - tells the engine the next position belongs to a new game
+func (e *externalEngine) newGame() error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.ready()
+}
+
+// goCommand - the go command of the limits, the default depth if none are set
+func goCommand(limits searchLimits) string {
+	args := []string{"go"}
+	if limits.depth > 0 {
+		args = append(args, fmt.Sprintf("depth %v", limits.depth))
+	}
+	if limits.nodes > 0 {
+		args = append(args, fmt.Sprintf("nodes %v", limits.nodes))
+	}
+	if limits.moveTime > 0 {
+		args = append(args, fmt.Sprintf("movetime %v", limits.moveTime.Milliseconds()))
+	}
+	if limits.wtime > 0 || limits.btime > 0 {
+		args = append(args, fmt.Sprintf("wtime %v btime %v winc %v binc %v",
+			limits.wtime.Milliseconds(), limits.btime.Milliseconds(), limits.winc.Milliseconds(), limits.binc.Milliseconds()))
+		if limits.movesToGo > 0 {
+			args = append(args, fmt.Sprintf("movestogo %v", limits.movesToGo))
+		}
+	}
+	if len(args) == 1 {
+		args = append(args, fmt.Sprintf("depth %v", defaultEngineDepth))
+	}
+	return strings.Join(args, " ")
+}
+
+// bestMove - asks the engine for its move in the position
+// an engine that overruns its time is told to stop, one that doesn't answer at all is an error
+// the output is drained first, a bestmove that came too late for the previous position isn't taken for this one
+func (e *externalEngine) bestMove(s *state.State, limits searchLimits) (*state.Move, error) {
+	if err := e.ready(); err != nil {
+		return nil, err
+	}
+	if err := e.send("position fen %v", s.FEN()); err != nil {
+		return nil, fmt.Errorf("engine %v: %w", e.name, err)
+	}
+	if err := e.send(goCommand(limits)); err != nil {
+		return nil, fmt.Errorf("engine %v: %w", e.name, err)
+	}
+
+	// engines manage their clock themselves, only running out of it is too long
+	timeout := externalEngineTimeout
+	clock := limits.wtime
+	if s.Turn == "black" {
+		clock = limits.btime
+	}
+	if limits.moveTime > 0 {
+		timeout = limits.moveTime + e.grace
+	} else if clock > 0 {
+		timeout = clock + e.grace
+	}
+	stopped := false
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := e.readLine(time.Until(deadline))
+		if err != nil && !stopped && e.send("stop") == nil {
+			stopped = true
+			deadline = time.Now().Add(e.grace)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("engine %v: no bestmove: %w", e.name, err)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "bestmove" {
+			continue
+		}
+		move, err := s.MoveFromUCI(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("engine %v: %w", e.name, err)
+		}
+		return move, nil
+	}
+}
+
+// close - asks the engine to quit, kills it if it doesn't
+func (e *externalEngine) close() error {
+	e.send("quit")
+	e.stdin.Close()
+	go func() {
+		for range e.lines {
+			// the output has to be read for the engine to exit
+		}
+	}()
+	exited := make(chan error, 1)
+	go func() { exited <- e.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(e.grace):
+		e.cmd.Process.Kill()
+		return <-exited
+	}
+}
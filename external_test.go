@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spunker/chess/game"
+	"github.com/spunker/chess/state"
+)
+
+// fakeEngineEnv - set when the test binary is started as the fake engine
+const fakeEngineEnv = "CHESS_FAKE_UCI_ENGINE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEngineEnv) != "" {
+		fakeUCIEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeUCIEngine - a UCI engine that plays the first legal move, sleeps through "go" when told to hang
+// (and answers stop late when told to be late) or exits on it when told to crash
+func fakeUCIEngine() {
+	var s *state.State
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			if len(fields) > 2 && fields[1] == "fen" {
+				s, _ = state.CreateStateFEN(strings.Join(fields[2:], " "))
+			}
+		case "go":
+			switch os.Getenv(fakeEngineEnv) {
+			case "hang", "late":
+				continue // only answers stop
+			case "crash":
+				os.Exit(1)
+			}
+			moves, _ := s.GetLegalMoves()
+			fmt.Println("info depth 1 score cp 0")
+			fmt.Printf("bestmove %v\n", moves[0].UCI())
+		case "stop":
+			if os.Getenv(fakeEngineEnv) == "late" {
+				time.Sleep(300 * time.Millisecond) // answers long after the grace period
+			}
+			moves, _ := s.GetLegalMoves()
+			fmt.Printf("bestmove %v\n", moves[0].UCI())
+		case "quit":
+			return
+		}
+	}
+}
+
+// startFakeEngine - the test binary running as the fake engine
+func startFakeEngine(t *testing.T, mode string) *externalEngine {
+	t.Helper()
+	t.Setenv(fakeEngineEnv, mode)
+	e, err := startExternalEngine(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.close() })
+	return e
+}
+
+func TestExternalEngine(t *testing.T) {
+	e := startFakeEngine(t, "play")
+	if e.name != "Fake Engine" {
+		t.Errorf("Expected the engine's name, got %q", e.name)
+	}
+	if err := e.newGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := game.StartGame("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		move, err := e.bestMove(g.State, searchLimits{depth: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := g.PlayMove(move); !ok || err != nil {
+			t.Fatalf("Expected a legal move, got %v (%v)", move.UCI(), err)
+		}
+	}
+	if g.Moves != 4 {
+		t.Errorf("Expected 4 moves played, got %v", g.Moves)
+	}
+
+	// an engine that overruns its time is told to stop
+	hanging := startFakeEngine(t, "hang")
+	hanging.grace = 100 * time.Millisecond
+	if move, err := hanging.bestMove(g.State, searchLimits{moveTime: 1}); err != nil || move == nil {
+		t.Errorf("Expected a move after stop, got %v", err)
+	}
+
+	// a bestmove that comes after the engine gave up on it isn't played in the next position
+	late := startFakeEngine(t, "late")
+	late.grace = 50 * time.Millisecond
+	if _, err := late.bestMove(g.State, searchLimits{moveTime: 1}); err == nil {
+		t.Fatal("Expected no move from an engine that doesn't answer stop in time")
+	}
+	next, err := game.StartGameFEN("4k3/8/8/8/8/8/8/4K2R b K - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	late.grace = time.Second
+	move, err := late.bestMove(next.State, searchLimits{moveTime: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := next.PlayMove(move); !ok || err != nil {
+		t.Errorf("Expected a move for the new position, got %v", move.UCI())
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	for limits, expected := range map[searchLimits]string{
+		{}:                                  "go depth 3",
+		{depth: 5, nodes: 1000}:             "go depth 5 nodes 1000",
+		{moveTime: 1500 * time.Millisecond}: "go movetime 1500",
+		{wtime: time.Minute, btime: 30 * time.Second, winc: time.Second, movesToGo: 20}: "go wtime 60000 btime 30000 winc 1000 binc 0 movestogo 20",
+	} {
+		if command := goCommand(limits); command != expected {
+			t.Errorf("Expected %q, got %q", expected, command)
+		}
+	}
+}
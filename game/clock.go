@@ -0,0 +1,57 @@
+package game
+
+import "time"
+
+// Clock - the time left of both sides, each side may have its own time control
+// a side set up without time plays untimed
+// the game doesn't run the clock, whoever times the moves calls Spend, and taking moves back doesn't give time back
+type Clock struct {
+	remaining map[string]time.Duration
+	increment map[string]time.Duration
+	timed     map[string]bool
+}
+
+// NewClock - a clock with the same time control for both sides
+func NewClock(base, increment time.Duration) *Clock {
+	c := &Clock{remaining: map[string]time.Duration{}, increment: map[string]time.Duration{}, timed: map[string]bool{}}
+	c.Set("white", base, increment)
+	c.Set("black", base, increment)
+	return c
+}
+
+// Set - gives a side its own time control
+func (c *Clock) Set(color string, base, increment time.Duration) {
+	c.remaining[color] = base
+	c.increment[color] = increment
+	c.timed[color] = base > 0
+}
+
+// Timed - true if the side plays with a time control
+func (c *Clock) Timed(color string) bool {
+	return c.timed[color]
+}
+
+// Remaining - the time left of a side, 0 for untimed sides
+func (c *Clock) Remaining(color string) time.Duration {
+	return c.remaining[color]
+}
+
+// Increment - the time a side gets for every move
+func (c *Clock) Increment(color string) time.Duration {
+	return c.increment[color]
+}
+
+// Spend - takes the time a move took from a side, false if its time ran out
+// the increment is only added when the move was in time, untimed sides never run out
+func (c *Clock) Spend(color string, elapsed time.Duration) bool {
+	if !c.timed[color] {
+		return true
+	}
+	c.remaining[color] -= elapsed
+	if c.remaining[color] <= 0 {
+		c.remaining[color] = 0
+		return false
+	}
+	c.remaining[color] += c.increment[color]
+	return true
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	c := NewClock(time.Minute, time.Second)
+	c.Set("black", 0, 0)
+	if !c.Timed("white") || c.Timed("black") {
+		t.Errorf("Expected only white to be timed")
+	}
+
+	if !c.Spend("white", 10*time.Second) || c.Remaining("white") != 51*time.Second {
+		t.Errorf("Expected 51s left after 10s with a 1s increment, got %v", c.Remaining("white"))
+	}
+	if !c.Spend("black", time.Hour) || c.Remaining("black") != 0 {
+		t.Errorf("Expected an untimed side never to run out, got %v", c.Remaining("black"))
+	}
+	if c.Spend("white", 51*time.Second) || c.Remaining("white") != 0 {
+		t.Errorf("Expected white to run out without getting the increment, got %v", c.Remaining("white"))
+	}
+}
+
+func TestUndoClock(t *testing.T) {
+	g, err := StartGame("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Clock = NewClock(time.Minute, 0)
+	g.Clock.Spend("white", 20*time.Second)
+	if err := g.PlayUCI("e2e4"); err != nil {
+		t.Fatal(err)
+	}
+	g.Undo()
+	if g.Clock.Remaining("white") != 40*time.Second {
+		t.Errorf("Expected the time spent to stay spent after taking the move back, got %v", g.Clock.Remaining("white"))
+	}
+}
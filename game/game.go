@@ -0,0 +1,457 @@
+// Package game - the lifecycle of a game of chess: its players, moves, clocks and result
+// front ends (the TUI, the engine protocols, the servers) play through a Game instead of the State directly
+package game
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spunker/chess/state"
+)
+
+// Results of a game
+const (
+	WhiteWins = "1-0"
+	BlackWins = "0-1"
+	Draw      = "1/2-1/2"
+	Ongoing   = "*"
+)
+
+// Termination - why a game ended, "" while it is still going
+type Termination string
+
+const (
+	Checkmate            Termination = "checkmate"
+	Stalemate            Termination = "stalemate"
+	Resignation          Termination = "resignation"
+	Timeout              Termination = "timeout"
+	DrawAgreement        Termination = "draw agreement"
+	Repetition           Termination = "repetition"
+	FiftyMoves           Termination = "fifty-move rule"
+	InsufficientMaterial Termination = "insufficient material"
+)
+
+// ErrGameOver - moves are rejected once the game has a result
+var ErrGameOver = errors.New("the game is over")
+
+// ErrNoDrawClaim - neither the fifty-move rule nor a threefold repetition lets a player claim a draw
+var ErrNoDrawClaim = errors.New("no draw to claim")
+
+type Game struct {
+	State                *state.State
+	Over                 bool
+	Moves                int
+	Outcome              string      // result of the game, Ongoing until it is over
+	Termination          Termination // why the game ended, "" while it is still going
+	Loser                string      // color that was mated, resigned or ran out of time, "" for draws
+	White                string      // name of the white player, "" if unknown
+	Black                string      // name of the black player
+	Clock                *Clock      // nil for games without a time control
+	legalMovesPreProcess []*state.Move
+	initial              *state.State  // the position the game started from, moves are taken back by replaying
+	initialHalfmoves     int           // halfmove clock of the starting position
+	history              []*state.Move // every move played since the start
+	future               []*state.Move // moves taken back, the last one is redone first
+	positions            []string      // the position after every move (the start first), to find repetitions
+	halfmoves            int           // moves since the last capture or pawn move, for the fifty-move rule
+}
+
+func StartGame(setup string) (*Game, error) {
+	newState, err := state.CreateState(setup)
+	if err != nil {
+		return nil, err
+	}
+	return newGame(newState)
+}
+
+// StartGameFEN - starts a game from a position in FEN, its halfmove clock counts towards the fifty-move rule
+func StartGameFEN(fen string) (*Game, error) {
+	newState, err := state.CreateStateFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	g, err := newGame(newState)
+	if err != nil {
+		return nil, err
+	}
+	if fields := strings.Fields(fen); len(fields) > 4 {
+		if halfmoves, err := strconv.Atoi(fields[4]); err == nil && halfmoves > 0 {
+			g.initialHalfmoves, g.halfmoves = halfmoves, halfmoves
+		}
+	}
+	if err := g.update(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// newGame - starts a game from the given state
+func newGame(s *state.State) (*Game, error) {
+	initial, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	result := &Game{
+		State:     s,
+		Over:      false,
+		Moves:     0,
+		Outcome:   Ongoing,
+		initial:   initial,
+		positions: []string{positionKey(s)},
+	}
+	result.legalMovesPreProcess, err = result.State.GetLegalMoves()
+	if err != nil {
+		return nil, err
+	}
+	if err := result.update(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StartPosition - a game from the fen ("" for the start position) with the moves (coordinate notation) played
+func StartPosition(fen string, moves []string) (*Game, error) {
+	var g *Game
+	var err error
+	if fen == "" {
+		g, err = StartGame("default")
+	} else {
+		g, err = StartGameFEN(fen)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, str := range moves {
+		if err := g.PlayUCI(str); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *Game) PlayMoveAlgebraic(alg string) (bool, error) {
+	res, err := g.PlayMove(state.FromAlgebraicToMove(alg))
+	return res, err
+}
+
+// PlayMove - plays the move if it is legal, ErrGameOver once the game has a result
+func (g *Game) PlayMove(move *state.Move) (bool, error) {
+	if g.Over {
+		return false, ErrGameOver
+	}
+	legalMoves, err := g.State.GetLegalMoves()
+	if err != nil {
+		return false, err
+	}
+	var legalMove *state.Move
+	isLegal := slices.ContainsFunc(legalMoves, func(m *state.Move) bool {
+		if res, err := m.Equal(move); res {
+			if err != nil {
+				return false
+			}
+			legalMove = m
+			return true
+		}
+		return false
+	})
+
+	if isLegal {
+		if err := g.apply(legalMove); err != nil {
+			return false, err
+		}
+		// playing the move that was taken back keeps the rest of the moves to redo
+		if n := len(g.future); n > 0 {
+			if same, _ := g.future[n-1].Equal(legalMove); same {
+				g.future = g.future[:n-1]
+			} else {
+				g.future = nil
+			}
+		}
+		return true, g.update()
+	}
+	return false, nil
+}
+
+// apply - plays a legal move and keeps track of what the draw rules need
+func (g *Game) apply(move *state.Move) error {
+	piece, err := g.State.Board.GetPiece(&move.From)
+	if err != nil {
+		return err
+	}
+	captured, err := g.State.Board.GetPiece(&move.To)
+	if err != nil {
+		return err
+	}
+	if _, err := g.State.ApplyMove(move); err != nil {
+		return err
+	}
+	if captured != nil || (piece != nil && piece.Type == "pawn") {
+		g.halfmoves = 0
+	} else {
+		g.halfmoves++
+	}
+	g.Moves++
+	g.history = append(g.history, move)
+	g.positions = append(g.positions, positionKey(g.State))
+	return nil
+}
+
+// positionKey - what makes two positions the same for repetitions: the pieces, the side to move and the castling rights
+func positionKey(s *state.State) string {
+	fields := strings.Fields(s.FEN())
+	return strings.Join(fields[:3], " ")
+}
+
+// update - sets the result once the position ends the game
+// repetitions and the fifty-move rule only give a right to claim the draw, see ClaimDraw
+func (g *Game) update() error {
+	isMate, err := g.State.IsCheckmate()
+	if err != nil {
+		return err
+	}
+	if isMate {
+		g.end(Checkmate, g.State.Turn)
+		return nil
+	}
+	isStale, err := g.State.IsStalemate()
+	if err != nil {
+		return err
+	}
+	switch {
+	case isStale:
+		g.end(Stalemate, "")
+	case g.State.IsInsufficientMaterial():
+		g.end(InsufficientMaterial, "")
+	}
+	return nil
+}
+
+// Claimable - the draw a player may claim in the current position (FiftyMoves or Repetition), "" for none
+// the game goes on until someone claims it, so front ends that only relay moves can play past it
+func (g *Game) Claimable() Termination {
+	switch {
+	case g.Over:
+		return ""
+	case g.halfmoves >= 100:
+		return FiftyMoves
+	case g.repetitions() >= 3:
+		return Repetition
+	}
+	return ""
+}
+
+// ClaimDraw - ends the game with the draw that can be claimed, ErrNoDrawClaim if there is none
+func (g *Game) ClaimDraw() error {
+	if g.Over {
+		return ErrGameOver
+	}
+	termination := g.Claimable()
+	if termination == "" {
+		return ErrNoDrawClaim
+	}
+	g.end(termination, "")
+	return nil
+}
+
+// repetitions - how often the current position occurred
+func (g *Game) repetitions() int {
+	current := g.positions[len(g.positions)-1]
+	count := 0
+	for _, key := range g.positions {
+		if key == current {
+			count++
+		}
+	}
+	return count
+}
+
+// end - ends the game, loser is "" for a draw
+func (g *Game) end(termination Termination, loser string) {
+	g.Over = true
+	g.Termination = termination
+	g.Loser = loser
+	switch loser {
+	case "white":
+		g.Outcome = BlackWins
+	case "black":
+		g.Outcome = WhiteWins
+	default:
+		g.Outcome = Draw
+	}
+}
+
+// Resign - the player of color gives up
+func (g *Game) Resign(color string) error {
+	if g.Over {
+		return ErrGameOver
+	}
+	g.end(Resignation, color)
+	return nil
+}
+
+// AgreeDraw - both players agree to a draw
+func (g *Game) AgreeDraw() error {
+	if g.Over {
+		return ErrGameOver
+	}
+	g.end(DrawAgreement, "")
+	return nil
+}
+
+// Timeout - the player of color ran out of time, a draw if the opponent has too little material to ever mate
+func (g *Game) Timeout(color string) error {
+	if g.Over {
+		return ErrGameOver
+	}
+	if !canMate(g.State.Board, opponent(color)) {
+		g.end(Timeout, "")
+		return nil
+	}
+	g.end(Timeout, color)
+	return nil
+}
+
+// canMate - false if color only has its king, or its king and a single bishop or knight
+func canMate(board *state.Board, color string) bool {
+	minors := 0
+	for _, piece := range board.GetPieces() {
+		if piece.Color != color {
+			continue
+		}
+		switch piece.Type {
+		case "king":
+		case "bishop", "knight":
+			minors++
+		default:
+			return true
+		}
+	}
+	return minors > 1
+}
+
+// opponent - the other color
+func opponent(color string) string {
+	if color == "white" {
+		return "black"
+	}
+	return "white"
+}
+
+// PlayUCI - plays a move in coordinate notation (e.g. e2e4)
+func (g *Game) PlayUCI(str string) error {
+	move, err := g.State.MoveFromUCI(str)
+	if err != nil {
+		return err
+	}
+	_, err = g.PlayMove(move)
+	return err
+}
+
+// History - the moves played since the start
+func (g *Game) History() []*state.Move {
+	return slices.Clone(g.history)
+}
+
+// Initial - a copy of the position the game started from
+func (g *Game) Initial() (*state.State, error) {
+	return g.initial.Copy()
+}
+
+// Undo - takes back the last move, false if no move was played yet
+// the game is replayed from the start so the State is exactly the one before the move (castling rights, previous moves)
+// a game over by resignation, time or agreement goes on again
+// the clock is left as it is, the time spent on the move is not given back (a side that lost on time still has none)
+func (g *Game) Undo() (bool, error) {
+	if len(g.history) == 0 {
+		return false, nil
+	}
+	last := g.history[len(g.history)-1]
+	if err := g.replay(g.history[:len(g.history)-1]); err != nil {
+		return false, err
+	}
+	g.future = append(g.future, last)
+	return true, nil
+}
+
+// Redo - plays the last move taken back again, false if there is none
+func (g *Game) Redo() (bool, error) {
+	if len(g.future) == 0 {
+		return false, nil
+	}
+	if g.Over {
+		return false, ErrGameOver
+	}
+	move := g.future[len(g.future)-1]
+	if err := g.apply(move); err != nil {
+		return false, err
+	}
+	g.future = g.future[:len(g.future)-1]
+	return true, g.update()
+}
+
+// replay - sets the game to the start position with the moves played
+func (g *Game) replay(moves []*state.Move) error {
+	s, err := g.initial.Copy()
+	if err != nil {
+		return err
+	}
+	g.State = s
+	g.Over, g.Outcome, g.Termination, g.Loser = false, Ongoing, "", ""
+	g.Moves = 0
+	g.history = nil
+	g.positions = []string{positionKey(s)}
+	g.halfmoves = g.initialHalfmoves
+	for _, move := range moves {
+		if err := g.apply(move); err != nil {
+			return err
+		}
+	}
+	return g.update()
+}
+
+// Result - the result of the game ("1-0", "0-1", "1/2-1/2" or "*" while it is still going) and why it ended
+func (g *Game) Result() (result string, reason string) {
+	return g.Outcome, g.Reason()
+}
+
+// Reason - why the game ended in words, "" while it is still going
+func (g *Game) Reason() string {
+	loser := colorName(g.Loser)
+	winner := colorName(opponent(g.Loser))
+	switch g.Termination {
+	case Checkmate:
+		return winner + " mates"
+	case Stalemate:
+		return "Stalemate"
+	case Resignation:
+		return loser + " resigns"
+	case Timeout:
+		if g.Loser == "" {
+			return "Time out against insufficient material"
+		}
+		return loser + " loses on time"
+	case DrawAgreement:
+		return "Draw agreed"
+	case Repetition:
+		return "Threefold repetition"
+	case FiftyMoves:
+		return "Fifty-move rule"
+	case InsufficientMaterial:
+		return "Insufficient material"
+	}
+	return ""
+}
+
+// colorName - the color capitalized, as it starts a sentence
+func colorName(color string) string {
+	if color == "" {
+		return ""
+	}
+	return strings.ToUpper(color[:1]) + color[1:]
+}
+
+func (g *Game) String() string {
+	return g.State.String()
+}
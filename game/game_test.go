@@ -0,0 +1,177 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		moves       []string
+		result      string
+		termination Termination
+		reason      string
+	}{
+		{"ongoing", "", []string{"e2e4"}, Ongoing, "", ""},
+		{"checkmate", "", []string{"f2f3", "e7e5", "g2g4", "d8h4"}, BlackWins, Checkmate, "Black mates"},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", []string{"g6h6"}, Draw, Stalemate, "Stalemate"},
+		{"insufficient material", "4k3/8/8/8/8/8/3r4/4K3 w - - 0 1", []string{"e1d2"}, Draw, InsufficientMaterial, "Insufficient material"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := StartPosition(test.fen, test.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, reason := g.Result()
+			if result != test.result || g.Termination != test.termination || reason != test.reason {
+				t.Errorf("Expected %v %q (%v), got %v %q (%v)", test.result, test.termination, test.reason, result, g.Termination, reason)
+			}
+			if g.Over != (test.result != Ongoing) {
+				t.Errorf("Expected over to be %v", test.result != Ongoing)
+			}
+		})
+	}
+}
+
+func TestClaimDraw(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		moves       []string
+		next        string // a move played past the draw
+		termination Termination
+		reason      string
+	}{
+		{"repetition", "", []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"}, "e2e4", Repetition, "Threefold repetition"},
+		{"fifty moves", "4k3/8/8/8/8/8/8/R3K3 w - - 99 80", []string{"a1a2"}, "e8d8", FiftyMoves, "Fifty-move rule"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := StartPosition(test.fen, test.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Over || g.Claimable() != test.termination {
+				t.Fatalf("Expected %q to be claimable in a game going on, got %q (over %v)", test.termination, g.Claimable(), g.Over)
+			}
+			if err := g.ClaimDraw(); err != nil {
+				t.Fatal(err)
+			}
+			if result, reason := g.Result(); result != Draw || g.Termination != test.termination || reason != test.reason {
+				t.Errorf("Expected a draw by %q (%v), got %v %q (%v)", test.termination, test.reason, result, g.Termination, reason)
+			}
+
+			// a game nobody claimed the draw in goes on
+			g, err = StartPosition(test.fen, append(slices.Clone(test.moves), test.next))
+			if err != nil {
+				t.Fatalf("Expected moves past the draw to be played, got %v", err)
+			}
+			if g.Over {
+				t.Errorf("Expected the game to go on, got %v", g.Termination)
+			}
+		})
+	}
+
+	g, err := StartGame("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ClaimDraw(); !errors.Is(err, ErrNoDrawClaim) {
+		t.Errorf("Expected no draw to claim at the start, got %v", err)
+	}
+}
+
+func TestGameOver(t *testing.T) {
+	g, err := StartGame("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Resign("white"); err != nil {
+		t.Fatal(err)
+	}
+	if result, reason := g.Result(); result != BlackWins || reason != "White resigns" {
+		t.Errorf("Expected 0-1 White resigns, got %v %v", result, reason)
+	}
+	if err := g.PlayUCI("e2e4"); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Expected a move after the resignation to be rejected, got %v", err)
+	}
+	if err := g.AgreeDraw(); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Expected a draw after the resignation to be rejected, got %v", err)
+	}
+
+	// a side that runs out of time only loses if the opponent could still mate
+	g, err = StartGameFEN("4k3/8/8/8/8/8/8/R3K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Timeout("black"); err != nil {
+		t.Fatal(err)
+	}
+	if result, reason := g.Result(); result != WhiteWins || reason != "Black loses on time" {
+		t.Errorf("Expected 1-0 Black loses on time, got %v %v", result, reason)
+	}
+	g, err = StartGameFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Timeout("white"); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := g.Result(); result != Draw || g.Termination != Timeout {
+		t.Errorf("Expected a draw by timeout, got %v %v", result, g.Termination)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	g, err := StartGameFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := g.State.FEN()
+	if err := g.PlayUCI("e1f1"); err != nil {
+		t.Fatal(err)
+	}
+	after := g.State.FEN()
+	if ok, err := g.Undo(); !ok || err != nil {
+		t.Fatalf("Expected the move to be taken back, got %v %v", ok, err)
+	}
+	if g.State.FEN() != before || len(g.State.PreviousMoves) != 0 || g.Moves != 0 {
+		t.Errorf("Expected %v without moves, got %v with %v moves", before, g.State.FEN(), len(g.State.PreviousMoves))
+	}
+	if ok, err := g.Redo(); !ok || err != nil {
+		t.Fatalf("Expected the move to be redone, got %v %v", ok, err)
+	}
+	if g.State.FEN() != after || len(g.State.PreviousMoves) != 1 {
+		t.Errorf("Expected %v after redo, got %v", after, g.State.FEN())
+	}
+	if ok, _ := g.Redo(); ok {
+		t.Error("Expected nothing left to redo")
+	}
+
+	// a different move drops the moves taken back
+	g.Undo()
+	if err := g.PlayUCI("e1g1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := g.Redo(); ok {
+		t.Error("Expected a new move to clear the moves to redo")
+	}
+
+	// taking back a mate goes on with the game
+	g, err = StartPosition("", []string{"f2f3", "e7e5", "g2g4", "d8h4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Undo()
+	if g.Over || g.Outcome != Ongoing {
+		t.Errorf("Expected the game to go on after taking back the mate, got %v", g.Outcome)
+	}
+	g.Redo()
+	if !g.Over || g.Termination != Checkmate {
+		t.Errorf("Expected the mate again after redo, got %v", g.Termination)
+	}
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spunker/chess/state"
+)
+
+// PGNHeader - the tags of a PGN game, empty tags are written as "?", the players default to the game's
+type PGNHeader struct {
+	Event       string
+	Site        string
+	Date        time.Time
+	Round       string
+	White       string
+	Black       string
+	Result      string // for games decided by other means than the board (time, resignation...), "" for the result of the position
+	Termination string // why the game ended, only written when set
+}
+
+// pgnLineLength - PGN import format lines are kept below 80 characters
+const pgnLineLength = 79
+
+// PGN - the game in portable game notation
+func (g *Game) PGN(header PGNHeader) (string, error) {
+	result := header.Result
+	if result == "" {
+		result = g.Outcome
+	}
+
+	var sb strings.Builder
+	date := "????.??.??"
+	if !header.Date.IsZero() {
+		date = header.Date.Format("2006.01.02")
+	}
+	tags := [][2]string{
+		{"Event", header.Event},
+		{"Site", header.Site},
+		{"Date", date},
+		{"Round", header.Round},
+		{"White", cmp.Or(header.White, g.White)},
+		{"Black", cmp.Or(header.Black, g.Black)},
+		{"Result", result},
+	}
+	if header.Termination != "" {
+		tags = append(tags, [2]string{"Termination", header.Termination})
+	}
+	startFEN := g.initial.FEN()
+	if startFEN != state.StartFEN {
+		tags = append(tags, [2]string{"SetUp", "1"}, [2]string{"FEN", startFEN})
+	}
+	for _, tag := range tags {
+		value := tag[1]
+		if value == "" {
+			value = "?"
+		}
+		fmt.Fprintf(&sb, "[%v %q]\n", tag[0], value)
+	}
+	sb.WriteString("\n")
+
+	movetext, err := g.movetext()
+	if err != nil {
+		return "", err
+	}
+	line := ""
+	for _, token := range append(movetext, result) {
+		if line != "" && len(line)+1+len(token) > pgnLineLength {
+			sb.WriteString(line + "\n")
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += token
+	}
+	sb.WriteString(line + "\n")
+	return sb.String(), nil
+}
+
+// movetext - the moves of the game in SAN with move numbers, replayed from the start position
+func (g *Game) movetext() ([]string, error) {
+	return SANMoves(g.initial, g.history)
+}
+
+// sanMoves - the moves from the position in SAN, with move numbers counted from 1
+func SANMoves(s *state.State, moves []*state.Move) ([]string, error) {
+	s, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	tokens := []string{}
+	number := 1
+	for i, move := range moves {
+		san, err := s.SAN(move)
+		if err != nil {
+			return nil, err
+		}
+		if s.Turn == "white" {
+			tokens = append(tokens, fmt.Sprintf("%v.", number))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%v...", number))
+		}
+		tokens = append(tokens, san)
+		if s.Turn == "black" {
+			number++
+		}
+		if _, err := s.ApplyMove(move); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// ParsePGN - the first game of a PGN text, with its moves played from the FEN tag or the start position
+// comments, variations and annotations are skipped
+func ParsePGN(text string) (*Game, error) {
+	fen := ""
+	var movetext strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			if movetext.Len() > 0 {
+				break // the tags of the next game
+			}
+			name, value, ok := strings.Cut(strings.Trim(line, "[]"), " ")
+			if ok && name == "FEN" {
+				fen = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+			continue
+		}
+		movetext.WriteString(line + "\n")
+	}
+
+	game, err := StartPosition(fen, nil)
+	if err != nil {
+		return nil, err
+	}
+	depth := 0 // of comments and variations
+	lineComment := false
+	var token strings.Builder
+	tokens := []string{}
+	for _, r := range movetext.String() + "\n" {
+		inToken := false
+		switch {
+		case lineComment:
+			lineComment = r != '\n'
+		case r == ';' && depth == 0:
+			lineComment = true
+		case r == '{' || r == '(':
+			depth++
+		case r == '}' || r == ')':
+			depth--
+		case depth > 0, r == ' ', r == '\n', r == '\t', r == '\r':
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+		if !inToken && token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+
+	for _, token := range tokens {
+		if token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*" {
+			return game, nil
+		}
+		// move numbers may be written against the move (1.e4), SAN has no dots
+		if dot := strings.LastIndex(token, "."); dot >= 0 {
+			token = token[dot+1:]
+		}
+		if token == "" || strings.HasPrefix(token, "$") {
+			continue
+		}
+		move, err := game.State.MoveFromSAN(token)
+		if err != nil {
+			return nil, fmt.Errorf("move %v: %w", game.Moves/2+1, err)
+		}
+		if _, err := game.PlayMove(move); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
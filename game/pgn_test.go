@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestPGN(t *testing.T) {
+	game, err := StartGameFEN("3r2k1/8/8/8/8/8/5PPP/R5K1 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range []string{"g8f8", "a1a8"} {
+		if err := game.PlayUCI(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pgn, err := game.PGN(PGNHeader{White: "A", Black: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[Event \"?\"]\n[Site \"?\"]\n[Date \"????.??.??\"]\n[Round \"?\"]\n[White \"A\"]\n[Black \"B\"]\n[Result \"*\"]\n" +
+		"[SetUp \"1\"]\n[FEN \"3r2k1/8/8/8/8/8/5PPP/R5K1 b - - 0 1\"]\n\n1... Kf8 2. Ra8 *\n"
+	if pgn != expected {
+		t.Errorf("Expected\n%v\ngot\n%v", expected, pgn)
+	}
+}
+
+func TestParsePGN(t *testing.T) {
+	text := `[Event "?"]
+[White "A"]
+
+1. e4 {best by test} e5 2.Nf3 (2. f4 exf4) Nc6 3. Bb5 a6 $1 4. O-O; castles
+Nf6 5. Re1 *
+
+[Event "the next game"]
+
+1. d4 *
+`
+	game, err := ParsePGN(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Moves != 9 || game.State.Turn != "black" {
+		t.Errorf("Expected 9 moves with black to move, got %v", game.Moves)
+	}
+	pgn, err := game.PGN(PGNHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.State.FEN() != game.State.FEN() {
+		t.Errorf("Expected the written game to read back the same, got %v", again.State.FEN())
+	}
+
+	if _, err := ParsePGN("1. e4 e4 *"); err == nil {
+		t.Errorf("Expected an illegal move to fail")
+	}
+}
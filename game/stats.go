@@ -1,4 +1,4 @@
-package main
+package game
 
 import (
 	chess "github.com/spunker/chess/state"
@@ -4,11 +4,13 @@ go 1.24.0
 
 toolchain go1.24.10
 
-require github.com/fatih/color v1.18.0
+require (
+	github.com/charmbracelet/bubbletea v1.3.10
+	github.com/fatih/color v1.18.0
+)
 
 require (
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
-	github.com/charmbracelet/bubbletea v1.3.10 // indirect
 	github.com/charmbracelet/colorprofile v0.2.3-0.20250311203215-f60798e515dc // indirect
 	github.com/charmbracelet/lipgloss v1.1.0 // indirect
 	github.com/charmbracelet/x/ansi v0.10.1 // indirect
@@ -1,7 +1,13 @@
 package main
 
+import (
+	"fmt"
+	"os"
+)
+
 func main() {
-	//_test()
-	//Interactive()
-	StartTui()
+	if err := runCommand(os.Args[1:]); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spunker/chess/game"
+)
+
+// Matches - games between two players from a set of openings, each opening played with both colors
+
+// defaultOpenings - openings used when no file is given, in the arguments of a UCI position command
+var defaultOpenings = []string{
+	"startpos moves e2e4 e7e5 g1f3 b8c6",
+	"startpos moves e2e4 c7c5 g1f3 d7d6",
+	"startpos moves e2e4 e7e6 d2d4 d7d5",
+	"startpos moves e2e4 c7c6 d2d4 d7d5",
+	"startpos moves d2d4 d7d5 c2c4 e7e6",
+	"startpos moves d2d4 g8f6 c2c4 g7g6",
+	"startpos moves c2c4 e7e5 b1c3 g8f6",
+	"startpos moves g1f3 d7d5 g2g3 g8f6",
+}
+
+// defaultMaxPlies - games still going after this many plies are drawn, a safety net for games the draw rules don't end
+const defaultMaxPlies = 200
+
+// maxEloDifference - the Elo difference reported for a score of 0% or 100%, where it has no finite value
+const maxEloDifference = 1000
+
+// matchOptions - how a match is played
+type matchOptions struct {
+	games    int
+	openings []string // arguments of a UCI position command, or plain FENs
+	maxPlies int
+	sprt     *sprt // stops the match early, nil to play every game
+	pgn      io.Writer
+	log      func(string)
+}
+
+// gameSummary - the outcome of a game of the match
+type gameSummary struct {
+	Round       int    `json:"round"`
+	White       string `json:"white"`
+	Black       string `json:"black"`
+	Opening     string `json:"opening"`
+	Result      string `json:"result"`
+	Termination string `json:"termination"`
+	Plies       int    `json:"plies"`
+}
+
+// matchSummary - the outcome of a match, counted from the first player's side
+type matchSummary struct {
+	PlayerA  string        `json:"player_a"`
+	PlayerB  string        `json:"player_b"`
+	Games    int           `json:"games"`
+	Wins     int           `json:"wins"`
+	Draws    int           `json:"draws"`
+	Losses   int           `json:"losses"`
+	Score    float64       `json:"score"`
+	Elo      float64       `json:"elo"`
+	EloError float64       `json:"elo_error"` // 95% confidence
+	SPRT     *sprtSummary  `json:"sprt,omitempty"`
+	Results  []gameSummary `json:"results"`
+}
+
+// sprtSummary - the state of the SPRT when the match ended
+type sprtSummary struct {
+	sprt
+	LLR        float64 `json:"llr"`
+	LowerBound float64 `json:"lower_bound"`
+	UpperBound float64 `json:"upper_bound"`
+	Result     string  `json:"result"` // "H0", "H1" or "" while undecided
+}
+
+// runMatch - the match command
+func runMatch(args []string) error {
+	flags := newFlagSet("match")
+	a := flags.String("a", "depth=2", "first player, comma separated key=value pairs:\n"+
+		"engine=path, profile=name, name=label, depth=n, nodes=n, movetime=ms, tc=seconds+inc, threads=n, quiescence=n, hash=mb, option.Name=value")
+	b := flags.String("b", "depth=2", "second player, like -a")
+	games := flags.Int("games", 16, "number of games, each opening is played with both colors")
+	openingsPath := flags.String("openings", "", "file with one opening per line, a FEN or \"startpos moves ...\" (default: a few common openings)")
+	maxPlies := flags.Int("maxplies", defaultMaxPlies, "games are drawn after this many plies")
+	sprtBounds := flags.String("sprt", "", "stop early with an SPRT of elo0,elo1 (e.g. 0,10)")
+	alpha := flags.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := flags.Float64("beta", 0.05, "SPRT false negative rate")
+	pgnPath := flags.String("pgn", "", "file the games are written to in PGN")
+	out := flags.String("out", "", "file the summary is written to in JSON")
+	flags.Parse(args)
+
+	opts := matchOptions{
+		games:    *games,
+		openings: defaultOpenings,
+		maxPlies: *maxPlies,
+		log:      func(line string) { fmt.Println(line) },
+	}
+	if *openingsPath != "" {
+		openings, err := readOpenings(*openingsPath)
+		if err != nil {
+			return err
+		}
+		opts.openings = openings
+	}
+	if *sprtBounds != "" {
+		elo0, elo1, ok := strings.Cut(*sprtBounds, ",")
+		test := &sprt{Alpha: *alpha, Beta: *beta}
+		var err0, err1 error
+		test.Elo0, err0 = strconv.ParseFloat(elo0, 64)
+		test.Elo1, err1 = strconv.ParseFloat(elo1, 64)
+		if !ok || err0 != nil || err1 != nil || test.Elo0 >= test.Elo1 {
+			return fmt.Errorf("invalid SPRT bounds %q, expected elo0,elo1 with elo0 < elo1", *sprtBounds)
+		}
+		opts.sprt = test
+	}
+	if *pgnPath != "" {
+		file, err := os.Create(*pgnPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		opts.pgn = file
+	}
+
+	first, err := parsePlayerSpec(*a)
+	if err != nil {
+		return err
+	}
+	defer first.player.close()
+	second, err := parsePlayerSpec(*b)
+	if err != nil {
+		return err
+	}
+	defer second.player.close()
+
+	summary, err := playMatch(first, second, opts)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readOpenings - the non empty lines of the file, # starts a comment
+func readOpenings(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	openings := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		openings = append(openings, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("no openings in %v", path)
+	}
+	return openings, nil
+}
+
+// openingGame - the game an opening line starts from
+func openingGame(opening string) (*game.Game, error) {
+	fields := strings.Fields(opening)
+	if len(fields) > 0 && (fields[0] == "startpos" || fields[0] == "fen") {
+		fen, moves := parsePosition(fields)
+		return game.StartPosition(fen, moves)
+	}
+	return game.StartGameFEN(opening)
+}
+
+// playMatch - plays the games of the match, the first player takes white in the odd rounds
+func playMatch(a, b *playerSpec, opts matchOptions) (*matchSummary, error) {
+	if len(opts.openings) == 0 {
+		return nil, errors.New("no openings")
+	}
+	summary := &matchSummary{PlayerA: a.player.String(), PlayerB: b.player.String(), Results: []gameSummary{}}
+	for round := 1; round <= opts.games; round++ {
+		white, black := a, b
+		if round%2 == 0 {
+			white, black = b, a
+		}
+		opening := opts.openings[((round-1)/2)%len(opts.openings)]
+		g, err := openingGame(opening)
+		if err != nil {
+			return nil, fmt.Errorf("opening %q: %w", opening, err)
+		}
+		result, termination, err := playMatchGame(g, white, black, opts.maxPlies)
+		if err != nil {
+			return nil, fmt.Errorf("round %v: %w", round, err)
+		}
+
+		played := gameSummary{
+			Round:       round,
+			White:       white.player.String(),
+			Black:       black.player.String(),
+			Opening:     opening,
+			Result:      result,
+			Termination: termination,
+			Plies:       g.Moves,
+		}
+		summary.add(played, white == a)
+		if opts.pgn != nil {
+			pgn, err := g.PGN(game.PGNHeader{
+				Event:       fmt.Sprintf("%v vs %v", summary.PlayerA, summary.PlayerB),
+				Site:        engineName,
+				Date:        time.Now(),
+				Round:       strconv.Itoa(round),
+				Result:      result,
+				Termination: termination,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := fmt.Fprintln(opts.pgn, pgn); err != nil {
+				return nil, err
+			}
+		}
+		if opts.log != nil {
+			opts.log(fmt.Sprintf("Round %v: %v - %v %v {%v}", round, played.White, played.Black, result, termination))
+			opts.log(summary.String())
+		}
+
+		if opts.sprt != nil {
+			summary.SPRT = opts.sprt.summary(summary.Wins, summary.Draws, summary.Losses)
+			if summary.SPRT.Result != "" {
+				if opts.log != nil {
+					opts.log(fmt.Sprintf("SPRT: %v accepted (LLR %.2f)", summary.SPRT.Result, summary.SPRT.LLR))
+				}
+				break
+			}
+		}
+	}
+	return summary, nil
+}
+
+// playMatchGame - plays the game to its end, returns the result and why the game ended
+// a player that fails to move, plays an illegal move or runs out of time loses
+// an engine that exited is an error, it would forfeit every game that is left
+func playMatchGame(g *game.Game, white, black *playerSpec, maxPlies int) (result, termination string, err error) {
+	if err := white.player.newGame(); err != nil {
+		return "", "", err
+	}
+	if white.player != black.player {
+		if err := black.player.newGame(); err != nil {
+			return "", "", err
+		}
+	}
+
+	g.White, g.Black = white.player.String(), black.player.String()
+	g.Clock = game.NewClock(0, 0)
+	g.Clock.Set("white", white.limits.wtime, white.limits.winc)
+	g.Clock.Set("black", black.limits.btime, black.limits.binc)
+	loss := map[string]string{"white": game.BlackWins, "black": game.WhiteWins}
+	for {
+		// the match adjudicates, a draw either player could claim ends the game
+		if g.Claimable() != "" {
+			if err := g.ClaimDraw(); err != nil {
+				return "", "", err
+			}
+		}
+		if g.Over {
+			result, reason := g.Result()
+			return result, reason, nil
+		}
+		if g.Moves >= maxPlies {
+			return game.Draw, "Move limit", nil
+		}
+
+		turn := g.State.Turn
+		side := white
+		if turn == "black" {
+			side = black
+		}
+		limits := side.limits
+		limits.wtime, limits.btime = g.Clock.Remaining("white"), g.Clock.Remaining("black")
+		limits.winc, limits.binc = g.Clock.Increment("white"), g.Clock.Increment("black")
+		colorName := strings.ToUpper(turn[:1]) + turn[1:]
+
+		start := time.Now()
+		move, err := side.player.bestMove(g.State, limits)
+		elapsed := time.Since(start)
+		if errors.Is(err, errEngineExited) {
+			return "", "", err
+		}
+		if err != nil {
+			return loss[turn], fmt.Sprintf("%v forfeits: %v", colorName, err), nil
+		}
+		if !g.Clock.Spend(turn, elapsed) {
+			if err := g.Timeout(turn); err != nil {
+				return "", "", err
+			}
+			continue
+		}
+		if ok, err := g.PlayMove(move); err != nil || !ok {
+			return loss[turn], fmt.Sprintf("%v forfeits: illegal move %v", colorName, move.UCI()), nil
+		}
+	}
+}
+
+// add - counts the game, aWhite tells which color the first player had
+func (m *matchSummary) add(game gameSummary, aWhite bool) {
+	m.Results = append(m.Results, game)
+	m.Games++
+	switch {
+	case game.Result == "1/2-1/2":
+		m.Draws++
+	case (game.Result == "1-0") == aWhite:
+		m.Wins++
+	default:
+		m.Losses++
+	}
+	m.Score = (float64(m.Wins) + float64(m.Draws)/2) / float64(m.Games)
+	m.Elo, m.EloError = eloEstimate(m.Wins, m.Draws, m.Losses)
+}
+
+// String - the running score of the match
+func (m *matchSummary) String() string {
+	return fmt.Sprintf("Score of %v vs %v: %v - %v - %v [%.3f] %v, Elo difference: %.1f +/- %.1f",
+		m.PlayerA, m.PlayerB, m.Wins, m.Losses, m.Draws, m.Score, m.Games, m.Elo, m.EloError)
+}
+
+// eloFromScore - the Elo difference that gives the expected score
+func eloFromScore(score float64) float64 {
+	if score <= 0 {
+		return -maxEloDifference
+	}
+	if score >= 1 {
+		return maxEloDifference
+	}
+	if score == 0.5 {
+		return 0 // not -0
+	}
+	return math.Max(-maxEloDifference, math.Min(maxEloDifference, -400*math.Log10(1/score-1)))
+}
+
+// scoreFromElo - the expected score of a player that is elo stronger
+func scoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// scoreVariance - mean score of the games and its variance per game
+func scoreVariance(wins, draws, losses int) (mean, variance float64) {
+	n := float64(wins + draws + losses)
+	if n == 0 {
+		return 0, 0
+	}
+	mean = (float64(wins) + float64(draws)/2) / n
+	variance = (float64(wins)*math.Pow(1-mean, 2) + float64(draws)*math.Pow(0.5-mean, 2) + float64(losses)*math.Pow(mean, 2)) / n
+	return mean, variance
+}
+
+// eloEstimate - the Elo difference of the score and the half width of its 95% confidence interval
+func eloEstimate(wins, draws, losses int) (elo, margin float64) {
+	n := float64(wins + draws + losses)
+	if n == 0 {
+		return 0, 0
+	}
+	mean, variance := scoreVariance(wins, draws, losses)
+	deviation := 1.959964 * math.Sqrt(variance/n)
+	margin = (eloFromScore(mean+deviation) - eloFromScore(mean-deviation)) / 2
+	return eloFromScore(mean), margin
+}
+
+// sprt - sequential probability ratio test of H0: the Elo difference is elo0 against H1: it is elo1
+type sprt struct {
+	Elo0  float64 `json:"elo0"`
+	Elo1  float64 `json:"elo1"`
+	Alpha float64 `json:"alpha"` // chance of accepting H1 when H0 holds
+	Beta  float64 `json:"beta"`  // chance of accepting H0 when H1 holds
+}
+
+// llr - the log likelihood ratio of the results, using the normal approximation of the score
+func (t sprt) llr(wins, draws, losses int) float64 {
+	mean, variance := scoreVariance(wins, draws, losses)
+	if variance == 0 {
+		return 0
+	}
+	n := float64(wins + draws + losses)
+	s0, s1 := scoreFromElo(t.Elo0), scoreFromElo(t.Elo1)
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// summary - the test after the results, decided once the LLR leaves its bounds
+func (t sprt) summary(wins, draws, losses int) *sprtSummary {
+	summary := &sprtSummary{
+		sprt:       t,
+		LLR:        t.llr(wins, draws, losses),
+		LowerBound: math.Log(t.Beta / (1 - t.Alpha)),
+		UpperBound: math.Log((1 - t.Beta) / t.Alpha),
+	}
+	if summary.LLR >= summary.UpperBound {
+		summary.Result = "H1"
+	} else if summary.LLR <= summary.LowerBound {
+		summary.Result = "H0"
+	}
+	return summary
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+func TestEloEstimate(t *testing.T) {
+	if elo, margin := eloEstimate(10, 0, 10); elo != 0 || margin <= 0 {
+		t.Errorf("Expected an even score to be 0 Elo with a margin, got %v +/- %v", elo, margin)
+	}
+	// 75% is about 191 Elo
+	if elo, _ := eloEstimate(60, 30, 10); math.Abs(elo-190.85) > 0.1 {
+		t.Errorf("Expected about 190.85 Elo, got %v", elo)
+	}
+	if elo, _ := eloEstimate(5, 0, 0); elo != maxEloDifference {
+		t.Errorf("Expected a perfect score to be capped, got %v", elo)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	test := sprt{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	if summary := test.summary(20, 60, 20); summary.Result != "" {
+		t.Errorf("Expected 100 even games to be undecided, got %+v", summary)
+	}
+	if summary := test.summary(400, 200, 200); summary.Result != "H1" || summary.LLR < summary.UpperBound {
+		t.Errorf("Expected a clearly stronger player to accept H1, got %+v", summary)
+	}
+	if summary := test.summary(200, 200, 400); summary.Result != "H0" {
+		t.Errorf("Expected a clearly weaker player to accept H0, got %+v", summary)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	a, err := parsePlayerSpec("name=one,depth=1,threads=1,quiescence=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parsePlayerSpec("name=two,depth=1,threads=1,quiescence=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pgn bytes.Buffer
+	summary, err := playMatch(a, b, matchOptions{
+		games:    2,
+		openings: []string{"6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1"},
+		maxPlies: 20,
+		pgn:      &pgn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Games != 2 || summary.Wins != 1 || summary.Losses != 1 {
+		t.Errorf("Expected each player to mate once with white, got %v", summary)
+	}
+	if summary.Results[1].White != "two" || summary.Results[1].Termination != "White mates" {
+		t.Errorf("Expected colors to alternate, got %+v", summary.Results)
+	}
+	if strings.Count(pgn.String(), `[Result "1-0"]`) != 2 || !strings.Contains(pgn.String(), "1. Rd8# 1-0") {
+		t.Errorf("Expected both games in the PGN, got:\n%v", pgn.String())
+	}
+}
+
+func TestMatchClock(t *testing.T) {
+	game, err := openingGame("startpos moves e2e4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow := &playerSpec{player: &botPlayer{name: "slow", weights: ai.DefaultWeights(), threads: 1, table: ai.NewTranspositionTable(1)}}
+	slow.limits.depth = 3
+	slow.limits.wtime, slow.limits.btime = time.Millisecond, time.Millisecond
+	result, termination, err := playMatchGame(game, slow, slow, defaultMaxPlies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "1-0" || termination != "Black loses on time" {
+		t.Errorf("Expected black to lose on time, got %v {%v}", result, termination)
+	}
+}
+
+func TestMatchAdjudicatesDraws(t *testing.T) {
+	game, err := openingGame("startpos moves g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot, err := parsePlayerSpec("depth=1,threads=1,quiescence=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, termination, err := playMatchGame(game, bot, bot, defaultMaxPlies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "1/2-1/2" || termination != "Threefold repetition" {
+		t.Errorf("Expected the repetition to be adjudicated, got %v %v", result, termination)
+	}
+}
+
+func TestMatchEngineExited(t *testing.T) {
+	crashing := &playerSpec{player: startFakeEngine(t, "crash")}
+	bot, err := parsePlayerSpec("name=bot,depth=1,threads=1,quiescence=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = playMatch(bot, crashing, matchOptions{games: 4, openings: defaultOpenings, maxPlies: 20})
+	if !errors.Is(err, errEngineExited) {
+		t.Errorf("Expected the match to stop when the engine exited, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spunker/chess/game"
+)
+
+// Multiplayer - games between two remote players over WebSockets
+//
+//	GET /rooms/{id}/ws?color=white|black|spectator&token=...&fen=...
+//
+// the first connection to a room creates it, from the start position or the fen
+// players get a token when they take a seat, connecting again with it takes the seat back
+// clients send {"type": "move", "move": "e2e4"} (coordinate notation or SAN), {"type": "resign"}
+// and {"type": "claim"} to claim the repetition or fifty-move draw in the state's "claimable"
+// every change is broadcast to everyone in the room as {"type": "state", ...}
+// a room is removed once everyone left, right away when its game is over, after roomIdleTimeout otherwise
+
+// roomIdleTimeout - how long an empty room with a game going on waits for its players to come back
+const roomIdleTimeout = 10 * time.Minute
+
+// multiplayerColors - the seats of a room
+var multiplayerColors = []string{"white", "black"}
+
+// multiplayerServer - the rooms being played
+type multiplayerServer struct {
+	mu      sync.Mutex
+	rooms   map[string]*room
+	origins []string      // hosts besides our own whose pages may connect, see originAllowed
+	idle    time.Duration // roomIdleTimeout, shorter in tests
+}
+
+// room - a game, its two seats and the spectators watching it
+type room struct {
+	mu         sync.Mutex
+	game       *game.Game
+	lastMove   string // SAN of the last move, "" before the first
+	seats      map[string]*seat
+	spectators map[*wsConn]bool
+
+	clients    int       // connections that joined and haven't left, guarded by the server's mutex
+	emptySince time.Time // when the last client left, guarded by the server's mutex
+}
+
+// seat - a player's place in a room, kept while the player is away
+type seat struct {
+	token string
+	conn  *wsConn // nil while disconnected
+}
+
+// clientMessage - what clients send
+type clientMessage struct {
+	Type string `json:"type"`
+	Move string `json:"move,omitempty"`
+}
+
+// roomState - what everyone in the room is told after every change
+type roomState struct {
+	Type       string   `json:"type"`
+	FEN        string   `json:"fen"`
+	Turn       string   `json:"turn"`
+	Moves      []string `json:"moves"`
+	LastMove   string   `json:"last_move,omitempty"` // in SAN
+	InCheck    bool     `json:"in_check"`
+	Result     string   `json:"result"`
+	Reason     string   `json:"reason,omitempty"`
+	Claimable  string   `json:"claimable,omitempty"` // the draw a player may claim, the game goes on until one does
+	White      bool     `json:"white"`               // whether the player is connected
+	Black      bool     `json:"black"`
+	Spectators int      `json:"spectators"`
+}
+
+// runMultiplayer - the multiplayer command, hosts rooms until the process is stopped
+func runMultiplayer(args []string) error {
+	flags := newFlagSet("multiplayer")
+	addr := flags.String("addr", "localhost:8081", "address to listen on")
+	origins := flags.String("origins", "", "comma separated hosts whose pages may connect besides the server's own, * for any")
+	flags.Parse(args)
+	allowed := []string{}
+	for _, host := range strings.Split(*origins, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			allowed = append(allowed, host)
+		}
+	}
+	fmt.Printf("Hosting rooms on ws://%v/rooms/{id}/ws\n", *addr)
+	return http.ListenAndServe(*addr, newMultiplayerServer(allowed...).handler())
+}
+
+// newMultiplayerServer - a server without rooms, origins are the hosts besides its own whose pages may connect
+func newMultiplayerServer(origins ...string) *multiplayerServer {
+	return &multiplayerServer{rooms: map[string]*room{}, origins: origins, idle: roomIdleTimeout}
+}
+
+// handler - the routes of the server
+func (ms *multiplayerServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rooms/{id}/ws", ms.join)
+	return mux
+}
+
+// enter - the room with the id, created on first use, counts the client until it calls exit
+func (ms *multiplayerServer) enter(id string, fen string) (*room, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if r, ok := ms.rooms[id]; ok {
+		r.clients++
+		return r, nil
+	}
+	var g *game.Game
+	var err error
+	if fen == "" {
+		g, err = game.StartGame("default")
+	} else {
+		g, err = game.StartGameFEN(fen)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &room{
+		game:       g,
+		seats:      map[string]*seat{},
+		spectators: map[*wsConn]bool{},
+		clients:    1,
+	}
+	ms.rooms[id] = r
+	return r, nil
+}
+
+// exit - the client left the room, an empty room is removed when its game is over or once it was idle for too long
+func (ms *multiplayerServer) exit(id string, r *room) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	r.clients--
+	if r.clients > 0 {
+		return
+	}
+	r.emptySince = time.Now()
+	r.mu.Lock()
+	over := r.game.Over
+	r.mu.Unlock()
+	if over {
+		delete(ms.rooms, id)
+		return
+	}
+	time.AfterFunc(ms.idle, func() { ms.expire(id, r) })
+}
+
+// expire - removes the room if nobody came back since it became empty
+func (ms *multiplayerServer) expire(id string, r *room) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.rooms[id] == r && r.clients == 0 && time.Since(r.emptySince) >= ms.idle {
+		delete(ms.rooms, id)
+	}
+}
+
+// join - the WebSocket endpoint of a room
+func (ms *multiplayerServer) join(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	id := req.PathValue("id")
+	r, err := ms.enter(id, query.Get("fen"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ms.exit(id, r)
+	conn, err := upgradeWebSocket(w, req, ms.origins)
+	if err != nil {
+		return
+	}
+	defer conn.close()
+
+	color, err := r.sit(conn, query.Get("color"), query.Get("token"))
+	if err != nil {
+		sendMessage(conn, map[string]string{"type": "error", "error": err.Error()})
+		return
+	}
+	defer r.leave(conn, color)
+
+	for {
+		_, data, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		var message clientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			sendMessage(conn, map[string]string{"type": "error", "error": "invalid message: " + err.Error()})
+			continue
+		}
+		if err := r.handle(color, message); err != nil {
+			sendMessage(conn, map[string]string{"type": "error", "error": err.Error()})
+		}
+	}
+}
+
+// sendMessage - sends a message as JSON, a peer that can't be written to is dropped by its read loop
+func sendMessage(conn *wsConn, message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	conn.writeText(data)
+}
+
+// newToken - a random token that lets a player take their seat back
+func newToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+// sit - takes a seat (or takes it back with its token), watches if none is asked for or free
+// returns the color the connection plays, "spectator" for spectators
+func (r *room) sit(conn *wsConn, color, token string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token != "" {
+		for _, c := range multiplayerColors {
+			if s := r.seats[c]; s != nil && s.token == token && (color == "" || color == c) {
+				if s.conn != nil {
+					s.conn.close() // the old connection of the player
+				}
+				s.conn = conn
+				return c, r.welcome(conn, c, token)
+			}
+		}
+		return "", errors.New("unknown token")
+	}
+
+	switch color {
+	case "":
+		color = "spectator"
+		for _, c := range multiplayerColors {
+			if r.seats[c] == nil {
+				color = c
+				break
+			}
+		}
+	case "white", "black":
+		if r.seats[color] != nil {
+			return "", fmt.Errorf("%v is already taken", color)
+		}
+	case "spectator":
+	default:
+		return "", fmt.Errorf("unknown color %q", color)
+	}
+
+	if color == "spectator" {
+		r.spectators[conn] = true
+		return color, r.welcome(conn, color, "")
+	}
+	token = newToken()
+	r.seats[color] = &seat{token: token, conn: conn}
+	return color, r.welcome(conn, color, token)
+}
+
+// welcome - tells the connection where it sits and everyone that it joined
+func (r *room) welcome(conn *wsConn, color, token string) error {
+	sendMessage(conn, struct {
+		Type  string `json:"type"`
+		Color string `json:"color"`
+		Token string `json:"token,omitempty"`
+	}{"joined", color, token})
+	return r.broadcast()
+}
+
+// leave - the connection went away, its seat is kept for the player to come back
+func (r *room) leave(conn *wsConn, color string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if color == "spectator" {
+		delete(r.spectators, conn)
+	} else if s := r.seats[color]; s != nil && s.conn == conn {
+		s.conn = nil
+	}
+	r.broadcast()
+}
+
+// handle - a message of the player (or spectator) of color
+func (r *room) handle(color string, message clientMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if color == "spectator" {
+		return errors.New("spectators can't play")
+	}
+	if r.game.Over {
+		return game.ErrGameOver
+	}
+
+	switch message.Type {
+	case "move":
+		if r.game.State.Turn != color {
+			return errors.New("not your turn")
+		}
+		move, err := parseMove(r.game.State, message.Move)
+		if err != nil {
+			return err
+		}
+		san, err := r.game.State.SAN(move)
+		if err != nil {
+			return err
+		}
+		if _, err := r.game.PlayMove(move); err != nil {
+			return err
+		}
+		r.lastMove = san
+	case "resign":
+		if err := r.game.Resign(color); err != nil {
+			return err
+		}
+	case "claim":
+		if err := r.game.ClaimDraw(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown message type %q", message.Type)
+	}
+	return r.broadcast()
+}
+
+// broadcast - sends the state of the room to everyone in it, the room must be locked
+func (r *room) broadcast() error {
+	result, reason := r.game.Result()
+	state := roomState{
+		Type:       "state",
+		FEN:        r.game.State.FEN(),
+		Turn:       r.game.State.Turn,
+		Moves:      []string{},
+		LastMove:   r.lastMove,
+		InCheck:    r.game.State.InCheck(),
+		Result:     result,
+		Reason:     reason,
+		Claimable:  string(r.game.Claimable()),
+		Spectators: len(r.spectators),
+	}
+	for _, move := range r.game.History() {
+		state.Moves = append(state.Moves, move.UCI())
+	}
+
+	conns := []*wsConn{}
+	for conn := range r.spectators {
+		conns = append(conns, conn)
+	}
+	for _, c := range multiplayerColors {
+		if seat := r.seats[c]; seat != nil && seat.conn != nil {
+			conns = append(conns, seat.conn)
+			if c == "white" {
+				state.White = true
+			} else {
+				state.Black = true
+			}
+		}
+	}
+	for _, conn := range conns {
+		sendMessage(conn, state)
+	}
+	return nil
+}
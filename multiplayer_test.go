@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverMessage - any message of the multiplayer server
+type serverMessage struct {
+	roomState
+	Color string `json:"color"`
+	Token string `json:"token"`
+	Error string `json:"error"`
+}
+
+// connect - joins the room of the test server
+func connect(t *testing.T, server *httptest.Server, query string) *wsClient {
+	t.Helper()
+	conn, err := dialWebSocket(strings.Replace(server.URL, "http://", "ws://", 1) + "/rooms/test/ws?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.close() })
+	return conn
+}
+
+// expect - reads messages until one of the type arrives
+func expect(t *testing.T, conn *wsClient, typ string) serverMessage {
+	t.Helper()
+	conn.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := conn.readMessage()
+		if err != nil {
+			t.Fatalf("Expected a %v message: %v", typ, err)
+		}
+		var message serverMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Type == typ {
+			return message
+		}
+	}
+}
+
+// send - sends a message to the server
+func send(t *testing.T, conn *wsClient, message clientMessage) {
+	t.Helper()
+	data, _ := json.Marshal(message)
+	if err := conn.writeText(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiplayer(t *testing.T) {
+	server := httptest.NewServer(newMultiplayerServer().handler())
+	defer server.Close()
+
+	white := connect(t, server, "color=white")
+	if joined := expect(t, white, "joined"); joined.Color != "white" || joined.Token == "" {
+		t.Fatalf("Expected to play white with a token, got %+v", joined)
+	}
+	black := connect(t, server, "")
+	blackToken := expect(t, black, "joined").Token
+	if state := expect(t, black, "state"); !state.White || !state.Black {
+		t.Errorf("Expected both players to be connected, got %+v", state)
+	}
+	if taken := connect(t, server, "color=white"); expect(t, taken, "error").Error != "white is already taken" {
+		t.Errorf("Expected white to be taken")
+	}
+	spectator := connect(t, server, "color=spectator")
+	expect(t, spectator, "joined")
+
+	send(t, black, clientMessage{Type: "move", Move: "e7e5"})
+	if failure := expect(t, black, "error"); failure.Error != "not your turn" {
+		t.Errorf("Expected black to wait for white, got %+v", failure)
+	}
+	send(t, white, clientMessage{Type: "move", Move: "e4"})
+	for _, conn := range []*wsClient{white, black, spectator} {
+		state := expect(t, conn, "state")
+		for len(state.Moves) == 0 {
+			state = expect(t, conn, "state")
+		}
+		if strings.Join(state.Moves, " ") != "e2e4" || state.LastMove != "e4" || state.Turn != "black" {
+			t.Errorf("Expected e4 to be broadcast, got %+v", state)
+		}
+	}
+	send(t, spectator, clientMessage{Type: "move", Move: "e7e5"})
+	expect(t, spectator, "error")
+
+	// black drops and comes back with the token
+	black.close()
+	if state := expect(t, white, "state"); state.Black {
+		t.Errorf("Expected black to be away, got %+v", state)
+	}
+	black = connect(t, server, "token="+blackToken)
+	if joined := expect(t, black, "joined"); joined.Color != "black" {
+		t.Errorf("Expected the token to give back black, got %+v", joined)
+	}
+	send(t, black, clientMessage{Type: "resign"})
+	state := expect(t, black, "state")
+	for state.Result == "*" {
+		state = expect(t, black, "state")
+	}
+	if state.Result != "1-0" || state.Reason != "Black resigns" {
+		t.Errorf("Expected black to have resigned, got %+v", state)
+	}
+	send(t, white, clientMessage{Type: "move", Move: "d4"})
+	if failure := expect(t, white, "error"); failure.Error != "the game is over" {
+		t.Errorf("Expected no moves after resigning, got %+v", failure)
+	}
+}
+
+// roomCount - the number of rooms once it settled on want, connections are closed in the background
+func roomCount(ms *multiplayerServer, want int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ms.mu.Lock()
+		n := len(ms.rooms)
+		ms.mu.Unlock()
+		if n == want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRoomCleanup(t *testing.T) {
+	ms := newMultiplayerServer()
+	ms.idle = 100 * time.Millisecond
+	server := httptest.NewServer(ms.handler())
+	defer server.Close()
+
+	// a finished game is removed as soon as everyone left
+	white := connect(t, server, "color=white")
+	expect(t, white, "joined")
+	send(t, white, clientMessage{Type: "resign"})
+	state := expect(t, white, "state")
+	for state.Result == "*" {
+		state = expect(t, white, "state")
+	}
+	white.close()
+	if n := roomCount(ms, 0); n != 0 {
+		t.Errorf("Expected the finished room to be removed, got %v rooms", n)
+	}
+
+	// a game going on waits for its players to come back
+	white = connect(t, server, "color=white")
+	token := expect(t, white, "joined").Token
+	send(t, white, clientMessage{Type: "move", Move: "e4"})
+	state = expect(t, white, "state")
+	for len(state.Moves) == 0 {
+		state = expect(t, white, "state")
+	}
+	white.close()
+	time.Sleep(ms.idle / 2)
+	white = connect(t, server, "token="+token)
+	if state := expect(t, white, "state"); strings.Join(state.Moves, " ") != "e2e4" || state.LastMove != "e4" {
+		t.Errorf("Expected the room to be kept while the player was away, got %+v", state)
+	}
+	time.Sleep(ms.idle)
+	if n := roomCount(ms, 1); n != 1 {
+		t.Errorf("Expected the room to be kept while the player is in it, got %v rooms", n)
+	}
+	white.close()
+	if n := roomCount(ms, 0); n != 0 {
+		t.Errorf("Expected the idle room to be removed, got %v rooms", n)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed []string
+		ok      bool
+	}{
+		{"", nil, true}, // not a browser
+		{"http://localhost:8081", nil, true},
+		{"http://evil.example", nil, false},
+		{"http://chess.example", []string{"chess.example"}, true},
+		{"http://evil.example", []string{"*"}, true},
+		{"null", nil, false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8081/rooms/test/ws", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if ok := originAllowed(r, test.allowed); ok != test.ok {
+			t.Errorf("Origin %q allowing %v: expected %v, got %v", test.origin, test.allowed, test.ok, ok)
+		}
+	}
+
+	server := httptest.NewServer(newMultiplayerServer().handler())
+	defer server.Close()
+	req := httptest.NewRequest("GET", server.URL+"/rooms/test/ws", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	rec := httptest.NewRecorder()
+	if _, err := upgradeWebSocket(rec, req, nil); err == nil || rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a foreign origin to be forbidden, got %v", rec.Code)
+	}
+}
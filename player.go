@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/state"
+)
+
+// player - picks the moves of one side, our own bot or an external engine
+type player interface {
+	String() string
+	newGame() error
+	bestMove(s *state.State, limits searchLimits) (*state.Move, error)
+	close() error
+}
+
+// String - the engine's name
+func (e *externalEngine) String() string {
+	return e.name
+}
+
+// botPlayer - our own engine with its weights
+type botPlayer struct {
+	name       string
+	weights    ai.Weights
+	threads    int
+	quiescence int
+	table      *ai.TranspositionTable
+}
+
+// String - the bot's name
+func (b *botPlayer) String() string {
+	return b.name
+}
+
+// newGame - forgets the positions of the previous game
+func (b *botPlayer) newGame() error {
+	b.table.Clear()
+	return nil
+}
+
+// bestMove - searches the position within the limits
+func (b *botPlayer) bestMove(s *state.State, limits searchLimits) (*state.Move, error) {
+	depth := limits.depth
+	budget := limits.timeBudget(s.Turn)
+	if depth <= 0 {
+		if budget > 0 || limits.nodes > 0 {
+			depth = maxEngineDepth
+		} else {
+			depth = defaultEngineDepth
+		}
+	}
+	stop := make(chan struct{})
+	if budget > 0 {
+		timer := time.AfterFunc(budget, func() { close(stop) })
+		defer timer.Stop()
+	}
+
+	s, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	result, err := ai.Search(s, &b.weights, &ai.SearchOptions{
+		Depth:      depth,
+		Threads:    b.threads,
+		Nodes:      limits.nodes,
+		Quiescence: b.quiescence,
+		Table:      b.table,
+		Stop:       stop,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Move == nil {
+		return nil, fmt.Errorf("%v found no move", b.name)
+	}
+	return result.Move, nil
+}
+
+// close - nothing to release
+func (b *botPlayer) close() error {
+	return nil
+}
+
+// playerSpec - a player and the limits it plays with
+type playerSpec struct {
+	player player
+	limits searchLimits // clocks are kept by the match, only their start values are set here
+}
+
+// parsePlayerSpec - a player from comma separated key=value pairs
+//
+//	engine=path   an external UCI engine instead of the bot
+//	profile=name  weights profile of the bot (or a path to a weights file)
+//	name=label    name in the results, the profile (or engine name) by default
+//	depth=n nodes=n movetime=ms tc=seconds+increment
+//	threads=n quiescence=n hash=mb   settings of the bot
+//	option.Name=value   a UCI option of the engine
+func parsePlayerSpec(spec string) (*playerSpec, error) {
+	values := map[string]string{}
+	options := [][2]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("player %q: expected key=value, got %q", spec, pair)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if option, ok := strings.CutPrefix(key, "option."); ok {
+			options = append(options, [2]string{option, value})
+			continue
+		}
+		values[key] = value
+	}
+
+	integer := func(key string, fallback int) (int, error) {
+		value, ok := values[key]
+		if !ok {
+			return fallback, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("player %q: %v must be a positive number, got %q", spec, key, value)
+		}
+		return n, nil
+	}
+	result := &playerSpec{}
+	var err error
+	if result.limits.depth, err = integer("depth", 0); err != nil {
+		return nil, err
+	}
+	if result.limits.nodes, err = integer("nodes", 0); err != nil {
+		return nil, err
+	}
+	moveTime, err := integer("movetime", 0)
+	if err != nil {
+		return nil, err
+	}
+	result.limits.moveTime = time.Duration(moveTime) * time.Millisecond
+	if tc, ok := values["tc"]; ok {
+		base, inc, err := parseTimeControl(tc)
+		if err != nil {
+			return nil, fmt.Errorf("player %q: %w", spec, err)
+		}
+		result.limits.wtime, result.limits.btime = base, base
+		result.limits.winc, result.limits.binc = inc, inc
+	}
+
+	if path, ok := values["engine"]; ok {
+		e, err := startExternalEngine(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range options {
+			if err := e.setOption(option[0], option[1]); err != nil {
+				e.close()
+				return nil, err
+			}
+		}
+		if name, ok := values["name"]; ok {
+			e.name = name
+		}
+		result.player = e
+		return result, nil
+	}
+
+	weights, err := ai.LoadProfile(values["profile"])
+	if err != nil {
+		return nil, err
+	}
+	threads, err := integer("threads", runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+	quiescence, err := integer("quiescence", defaultQuiescence)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := integer("hash", defaultHash)
+	if err != nil {
+		return nil, err
+	}
+	name := values["name"]
+	if name == "" {
+		name = values["profile"]
+	}
+	if name == "" {
+		name = ai.DefaultProfile
+	}
+	result.player = &botPlayer{
+		name:       name,
+		weights:    *weights,
+		threads:    max(threads, 1),
+		quiescence: quiescence,
+		table:      ai.NewTranspositionTable(max(hash, 1)),
+	}
+	return result, nil
+}
+
+// parseTimeControl - a time control as seconds+increment (e.g. 10+0.1, 60)
+func parseTimeControl(tc string) (base, inc time.Duration, err error) {
+	baseStr, incStr, _ := strings.Cut(tc, "+")
+	seconds, err := strconv.ParseFloat(baseStr, 64)
+	if err != nil || seconds <= 0 {
+		return 0, 0, fmt.Errorf("invalid time control %q", tc)
+	}
+	increment := 0.0
+	if incStr != "" {
+		if increment, err = strconv.ParseFloat(incStr, 64); err != nil || increment < 0 {
+			return 0, 0, fmt.Errorf("invalid time control %q", tc)
+		}
+	}
+	return time.Duration(seconds * float64(time.Second)), time.Duration(increment * float64(time.Second)), nil
+}
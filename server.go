@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
+	"github.com/spunker/chess/state"
+)
+
+// HTTP API - games and analysis as JSON over HTTP, meant to run on localhost
+//
+//	POST   /games                  {"setup": "default"} or {"fen": "..."}, creates a game
+//	GET    /games/{id}             the game
+//	DELETE /games/{id}             forgets the game
+//	GET    /games/{id}/moves       the legal moves
+//	POST   /games/{id}/moves       {"move": "e2e4"} in coordinate notation or SAN
+//	POST   /games/{id}/engine-move {"depth": 3, "movetime_ms": 1000}, the engine plays a move
+//	POST   /games/{id}/claim-draw  ends the game with the repetition or fifty-move draw in "claimable"
+//	GET    /games/{id}/pgn         the game in PGN
+//	POST   /analyze                {"fen": "...", "depth": 3, "movetime_ms": 1000, "lines": 1}
+//	                               the move time is shared by the lines, at most 8 lines are reported
+
+// maxServerMoveTime - the longest an engine request may think
+const maxServerMoveTime = time.Minute
+
+// maxServerLines - the most lines an analysis reports, every line is a search of its own
+const maxServerLines = 8
+
+// gameServer - the games hosted by the API
+type gameServer struct {
+	mu      sync.Mutex
+	games   map[int]*serverGame
+	nextID  int
+	weights ai.Weights
+	table   *ai.TranspositionTable
+	threads int
+}
+
+// serverGame - a hosted game, requests on the same game are handled one at a time
+type serverGame struct {
+	mu      sync.Mutex
+	id      int
+	game    *game.Game
+	created time.Time
+}
+
+// runServe - the serve command, answers the API until the process is stopped
+func runServe(args []string) error {
+	flags := newFlagSet("serve")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	profile := flags.String("profile", "", "weights profile of the engine, a profile name or a path to a .json/.toml file")
+	flags.Parse(args)
+	weights, err := ai.LoadProfile(*profile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Serving on http://%v\n", *addr)
+	return http.ListenAndServe(*addr, newGameServer(*weights).handler())
+}
+
+// newGameServer - a server without games
+func newGameServer(weights ai.Weights) *gameServer {
+	return &gameServer{
+		games:   map[int]*serverGame{},
+		nextID:  1,
+		weights: weights,
+		table:   ai.NewTranspositionTable(defaultHash),
+		threads: runtime.NumCPU(),
+	}
+}
+
+// handler - the routes of the API
+func (gs *gameServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /games", gs.createGame)
+	mux.HandleFunc("GET /games/{id}", gs.withGame(gs.getGame))
+	mux.HandleFunc("DELETE /games/{id}", gs.deleteGame)
+	mux.HandleFunc("GET /games/{id}/moves", gs.withGame(gs.legalMoves))
+	mux.HandleFunc("POST /games/{id}/moves", gs.withGame(gs.playMove))
+	mux.HandleFunc("POST /games/{id}/engine-move", gs.withGame(gs.engineMove))
+	mux.HandleFunc("POST /games/{id}/claim-draw", gs.withGame(gs.claimDraw))
+	mux.HandleFunc("GET /games/{id}/pgn", gs.withGame(gs.pgn))
+	mux.HandleFunc("POST /analyze", gs.analyze)
+	return mux
+}
+
+// gameView - a game as returned by the API
+type gameView struct {
+	ID      int      `json:"id"`
+	FEN     string   `json:"fen"`
+	Turn    string   `json:"turn"`
+	Moves   []string `json:"moves"`
+	InCheck bool     `json:"in_check"`
+	Result  string   `json:"result"`
+	Reason  string   `json:"reason,omitempty"`
+
+	Claimable string `json:"claimable,omitempty"` // the draw a player may claim, the game goes on until one does
+}
+
+// moveView - a move in both notations
+type moveView struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+}
+
+// searchView - what the engine found
+type searchView struct {
+	Move   string   `json:"move"`
+	SAN    string   `json:"san"`
+	Score  int      `json:"score"` // centipawns from the side to move
+	MateIn int      `json:"mate_in,omitempty"`
+	Depth  int      `json:"depth"`
+	Nodes  int      `json:"nodes"`
+	TimeMS int64    `json:"time_ms"`
+	PV     []string `json:"pv"`
+}
+
+// searchRequest - the limits of an engine move or an analysis
+type searchRequest struct {
+	FEN        string `json:"fen"`
+	Depth      int    `json:"depth"`
+	MoveTimeMS int    `json:"movetime_ms"`
+	Lines      int    `json:"lines"`
+}
+
+// writeJSON - writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError - writes an error as {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readJSON - decodes the request body, an empty body leaves value untouched
+func readJSON(r *http.Request, value any) error {
+	err := json.NewDecoder(r.Body).Decode(value)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// lookup - the game with the id in the path
+func (gs *gameServer) lookup(r *http.Request) (*serverGame, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid game id %q", r.PathValue("id"))
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	sg, ok := gs.games[id]
+	if !ok {
+		return nil, fmt.Errorf("game %v not found", id)
+	}
+	return sg, nil
+}
+
+// withGame - looks up and locks the game of the request for the handler
+func (gs *gameServer) withGame(handle func(http.ResponseWriter, *http.Request, *serverGame)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sg, err := gs.lookup(r)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		sg.mu.Lock()
+		defer sg.mu.Unlock()
+		handle(w, r, sg)
+	}
+}
+
+// view - the game as returned by the API
+func (sg *serverGame) view() (*gameView, error) {
+	result, reason := sg.game.Result()
+	moves := make([]string, len(sg.game.History()))
+	for i, move := range sg.game.History() {
+		moves[i] = move.UCI()
+	}
+	return &gameView{
+		ID:      sg.id,
+		FEN:     sg.game.State.FEN(),
+		Turn:    sg.game.State.Turn,
+		Moves:   moves,
+		InCheck: sg.game.State.InCheck(),
+		Result:  result,
+		Reason:  reason,
+
+		Claimable: string(sg.game.Claimable()),
+	}, nil
+}
+
+// writeGame - answers with the game
+func writeGame(w http.ResponseWriter, status int, sg *serverGame) {
+	view, err := sg.view()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, status, view)
+}
+
+func (gs *gameServer) createGame(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Setup string `json:"setup"`
+		FEN   string `json:"fen"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var g *game.Game
+	var err error
+	switch {
+	case body.Setup != "" && body.FEN != "":
+		err = errors.New("either a setup or a fen, not both")
+	case body.FEN != "":
+		g, err = game.StartGameFEN(body.FEN)
+	case body.Setup != "":
+		g, err = game.StartGame(body.Setup)
+	default:
+		g, err = game.StartGame("default")
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	gs.mu.Lock()
+	sg := &serverGame{id: gs.nextID, game: g, created: time.Now()}
+	gs.games[sg.id] = sg
+	gs.nextID++
+	gs.mu.Unlock()
+	writeGame(w, http.StatusCreated, sg)
+}
+
+func (gs *gameServer) getGame(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	writeGame(w, http.StatusOK, sg)
+}
+
+func (gs *gameServer) deleteGame(w http.ResponseWriter, r *http.Request) {
+	sg, err := gs.lookup(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	gs.mu.Lock()
+	delete(gs.games, sg.id)
+	gs.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (gs *gameServer) legalMoves(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	legalMoves, err := sg.game.State.GetLegalMoves()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	moves := make([]moveView, len(legalMoves))
+	for i, move := range legalMoves {
+		san, err := sg.game.State.SAN(move)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		moves[i] = moveView{UCI: move.UCI(), SAN: san}
+	}
+	writeJSON(w, http.StatusOK, moves)
+}
+
+// parseMove - a legal move in coordinate notation or SAN
+func parseMove(s *state.State, str string) (*state.Move, error) {
+	if move, err := s.MoveFromUCI(str); err == nil {
+		return move, nil
+	}
+	return s.MoveFromSAN(str)
+}
+
+func (gs *gameServer) playMove(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	var body struct {
+		Move string `json:"move"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := gs.checkNotOver(sg); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	move, err := parseMove(sg.game.State, body.Move)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if _, err := sg.game.PlayMove(move); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeGame(w, http.StatusOK, sg)
+}
+
+// checkNotOver - an error once the game has a result
+func (gs *gameServer) checkNotOver(sg *serverGame) error {
+	if sg.game.Over {
+		result, reason := sg.game.Result()
+		return fmt.Errorf("game is over: %v %v", result, reason)
+	}
+	return nil
+}
+
+func (gs *gameServer) claimDraw(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	if err := gs.checkNotOver(sg); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err := sg.game.ClaimDraw(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeGame(w, http.StatusOK, sg)
+}
+
+func (gs *gameServer) engineMove(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	var body searchRequest
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := gs.checkNotOver(sg); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	results, err := gs.search(r.Context(), sg.game.State, body, 1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	view, err := newSearchView(sg.game.State, results[0], ai.PawnValue(&gs.weights))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := sg.game.PlayMove(results[0].Move); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	game, err := sg.view()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Search *searchView `json:"search"`
+		Game   *gameView   `json:"game"`
+	}{view, game})
+}
+
+func (gs *gameServer) pgn(w http.ResponseWriter, r *http.Request, sg *serverGame) {
+	pgn, err := sg.game.PGN(game.PGNHeader{
+		Event: "HTTP API game",
+		Site:  r.Host,
+		Date:  sg.created,
+		Round: strconv.Itoa(sg.id),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	io.WriteString(w, pgn)
+}
+
+func (gs *gameServer) analyze(w http.ResponseWriter, r *http.Request) {
+	var body searchRequest
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	fen := body.FEN
+	if fen == "" {
+		fen = state.StartFEN
+	}
+	s, err := state.CreateStateFEN(fen)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	lines := min(max(body.Lines, 1), maxServerLines)
+	results, err := gs.search(r.Context(), s, body, lines)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	views := []*searchView{}
+	for _, result := range results {
+		view, err := newSearchView(s, result, ai.PawnValue(&gs.weights))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		views = append(views, view)
+	}
+	writeJSON(w, http.StatusOK, struct {
+		FEN   string        `json:"fen"`
+		Lines []*searchView `json:"lines"`
+	}{s.FEN(), views})
+}
+
+// search - searches the position within the limits of the request, stops early when the client goes away
+// with several lines every line gets its share of the move time, a shared deadline would leave the last lines at depth 1
+func (gs *gameServer) search(ctx context.Context, s *state.State, req searchRequest, lines int) ([]*ai.SearchResult, error) {
+	depth := min(req.Depth, maxEngineDepth)
+	moveTime := min(time.Duration(req.MoveTimeMS)*time.Millisecond, maxServerMoveTime)
+	if depth <= 0 {
+		if moveTime > 0 {
+			depth = maxEngineDepth
+		} else {
+			depth = defaultEngineDepth
+		}
+	}
+
+	s, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	weights := gs.weights
+	opts := &ai.SearchOptions{
+		Depth:      depth,
+		Threads:    gs.threads,
+		Quiescence: defaultQuiescence,
+		Table:      gs.table,
+		MoveTime:   moveTime / time.Duration(lines),
+		Stop:       ctx.Done(),
+	}
+	results, err := ai.SearchMultiPV(s, &weights, opts, lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errors.New("no legal moves")
+	}
+	return results, nil
+}
+
+// newSearchView - a search result of the position s, pawn is what a pawn is worth in its score
+func newSearchView(s *state.State, result *ai.SearchResult, pawn float64) (*searchView, error) {
+	san, err := s.SAN(result.Move)
+	if err != nil {
+		return nil, err
+	}
+	mateIn := 0
+	if result.Mate {
+		mateIn = result.MateIn
+		if s.Turn == "black" {
+			mateIn = -mateIn
+		}
+	}
+	return &searchView{
+		Move:   result.Move.UCI(),
+		SAN:    san,
+		Score:  centipawns(result.Score, pawn, s.Turn),
+		MateIn: mateIn,
+		Depth:  result.Depth,
+		Nodes:  result.Nodes,
+		TimeMS: result.Time.Milliseconds(),
+		PV:     strings.Fields(coordinatePV(result)),
+	}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+// request - sends a request to the API and decodes the JSON answer into value
+func request(t *testing.T, server *httptest.Server, method, path, body string, value any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if value != nil {
+		if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+			t.Fatalf("%v %v: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	gs := newGameServer(ai.DefaultWeights())
+	gs.threads = 1
+	server := httptest.NewServer(gs.handler())
+	defer server.Close()
+
+	var game gameView
+	if status := request(t, server, "POST", "/games", `{"setup": "default"}`, &game); status != http.StatusCreated {
+		t.Fatalf("Expected a created game, got status %v", status)
+	}
+
+	var moves []moveView
+	request(t, server, "GET", "/games/1/moves", "", &moves)
+	if len(moves) != 20 {
+		t.Errorf("Expected 20 legal moves, got %v", len(moves))
+	}
+
+	request(t, server, "POST", "/games/1/moves", `{"move": "e2e4"}`, &game)
+	request(t, server, "POST", "/games/1/moves", `{"move": "e5"}`, &game)
+	if strings.Join(game.Moves, " ") != "e2e4 e7e5" || game.Turn != "white" {
+		t.Errorf("Expected e2e4 e7e5 with white to move, got %+v", game)
+	}
+
+	var failure map[string]string
+	if status := request(t, server, "POST", "/games/1/moves", `{"move": "e2e4"}`, &failure); status != http.StatusUnprocessableEntity || failure["error"] == "" {
+		t.Errorf("Expected an illegal move to be rejected, got status %v %v", status, failure)
+	}
+
+	var engineMove struct {
+		Search searchView `json:"search"`
+		Game   gameView   `json:"game"`
+	}
+	request(t, server, "POST", "/games/1/engine-move", `{"depth": 1}`, &engineMove)
+	if engineMove.Search.Move == "" || len(engineMove.Game.Moves) != 3 || engineMove.Game.Turn != "black" {
+		t.Errorf("Expected the engine to play the third move, got %+v", engineMove)
+	}
+
+	resp, err := server.Client().Get(server.URL + "/games/1/pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgn, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(pgn), `[Result "*"]`) || !strings.Contains(string(pgn), "1. e4 e5 2. "+engineMove.Search.SAN+" *") {
+		t.Errorf("Unexpected PGN:\n%v", string(pgn))
+	}
+
+	// mate in one, black to move
+	request(t, server, "POST", "/games", `{"fen": "3r2k1/8/8/8/8/8/5PPP/6K1 b - - 0 1"}`, &game)
+	request(t, server, "POST", "/games/2/engine-move", `{"depth": 2}`, &engineMove)
+	if engineMove.Search.SAN != "Rd1#" || engineMove.Search.MateIn != 1 || engineMove.Game.Result != "0-1" {
+		t.Errorf("Expected Rd1# to end the game, got %+v", engineMove)
+	}
+	if status := request(t, server, "POST", "/games/2/moves", `{"move": "g1h1"}`, nil); status != http.StatusConflict {
+		t.Errorf("Expected no moves after mate, got status %v", status)
+	}
+
+	var analysis struct {
+		Lines []searchView `json:"lines"`
+	}
+	request(t, server, "POST", "/analyze", `{"depth": 1, "lines": 2}`, &analysis)
+	if len(analysis.Lines) != 2 {
+		t.Errorf("Expected 2 lines of analysis, got %+v", analysis)
+	}
+	request(t, server, "POST", "/analyze", `{"depth": 1, "lines": 100}`, &analysis)
+	if len(analysis.Lines) != maxServerLines {
+		t.Errorf("Expected the lines to be capped at %v, got %v", maxServerLines, len(analysis.Lines))
+	}
+	// every line gets its own share of the time, not what is left of it
+	request(t, server, "POST", "/analyze", `{"movetime_ms": 600, "lines": 3}`, &analysis)
+	for i, line := range analysis.Lines {
+		if line.TimeMS < 100 {
+			t.Errorf("Expected line %v to get about a third of the time, got %+v", i+1, line)
+		}
+	}
+
+	if status := request(t, server, "DELETE", "/games/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("Expected the game to be deleted, got status %v", status)
+	}
+	if status := request(t, server, "GET", "/games/1", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected a deleted game to be gone, got status %v", status)
+	}
+}
+
+func TestServerClaimDraw(t *testing.T) {
+	server := httptest.NewServer(newGameServer(ai.DefaultWeights()).handler())
+	defer server.Close()
+
+	var game gameView
+	request(t, server, "POST", "/games", `{"setup": "default"}`, &game)
+	if status := request(t, server, "POST", "/games/1/claim-draw", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected no draw to claim at the start, got status %v", status)
+	}
+	for _, move := range []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"} {
+		request(t, server, "POST", "/games/1/moves", `{"move": "`+move+`"}`, &game)
+	}
+	if game.Result != "*" || game.Claimable != "repetition" {
+		t.Errorf("Expected the repetition to be claimable in a game going on, got %+v", game)
+	}
+	game = gameView{}
+	request(t, server, "POST", "/games/1/claim-draw", "", &game)
+	if game.Result != "1/2-1/2" || game.Reason != "Threefold repetition" || game.Claimable != "" {
+		t.Errorf("Expected the claimed draw, got %+v", game)
+	}
+}
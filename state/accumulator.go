@@ -0,0 +1,28 @@
+package state
+
+// Accumulator - keeps values up to date that only change when pieces are placed on or removed from the board
+// (material, piece-square scores, ...), so they don't have to be recomputed by walking the whole board
+// moves are applied as a removal from the old square and a placement on the new one (captures remove the
+// captured piece first), and because positions are searched on copies the accumulator of a position is
+// never changed back: copying the board copies the accumulator, and the original keeps its old values
+type Accumulator interface {
+	Add(piece *Piece, pos Position)
+	Remove(piece *Piece, pos Position)
+	Copy() Accumulator
+}
+
+// SetAccumulator - attaches an accumulator to the board, and adds all pieces already on it
+func (b *Board) SetAccumulator(accumulator Accumulator) {
+	b.accumulator = accumulator
+	if accumulator == nil {
+		return
+	}
+	for _, piece := range b.GetPieces() {
+		accumulator.Add(piece, piece.Pos)
+	}
+}
+
+// Accumulator - returns the accumulator attached to the board (nil if there is none)
+func (b *Board) Accumulator() Accumulator {
+	return b.accumulator
+}
@@ -0,0 +1,97 @@
+package state
+
+// Attack queries
+// possible moves are not the same as attacks: a pawn doesn't attack the square in front of it and
+// every piece defends the squares of its own pieces, so these work on the geometry of the pieces instead
+
+// Attacks - checks whether the piece attacks (or defends) the given position on the board
+func (p *Piece) Attacks(board *Board, pos *Position) bool {
+	if !board.isInBounds(pos) {
+		return false
+	}
+	dx, dy := pos.X-p.Pos.X, pos.Y-p.Pos.Y
+	if dx == 0 && dy == 0 {
+		return false
+	}
+	switch p.Type {
+	case "pawn":
+		forward := 1
+		if p.Color == "black" {
+			forward = -1
+		}
+		return dy == forward && (dx == 1 || dx == -1)
+	case "knight":
+		return (abs(dx) == 1 && abs(dy) == 2) || (abs(dx) == 2 && abs(dy) == 1)
+	case "king":
+		return abs(dx) <= 1 && abs(dy) <= 1
+	case "rook":
+		return (dx == 0 || dy == 0) && board.isRayClear(p.Pos, *pos)
+	case "bishop":
+		return abs(dx) == abs(dy) && board.isRayClear(p.Pos, *pos)
+	case "queen":
+		return (dx == 0 || dy == 0 || abs(dx) == abs(dy)) && board.isRayClear(p.Pos, *pos)
+	}
+	return false
+}
+
+// AttackersOf - returns all pieces of the given color attacking the given position
+func (b *Board) AttackersOf(pos *Position, color string) []*Piece {
+	result := []*Piece{}
+	for _, piece := range b.GetPieces() {
+		if piece.Color == color && piece.Attacks(b, pos) {
+			result = append(result, piece)
+		}
+	}
+	return result
+}
+
+// IsAttacked - checks whether any piece of the given color attacks the given position
+func (b *Board) IsAttacked(pos *Position, color string) bool {
+	for _, piece := range b.GetPieces() {
+		if piece.Color == color && piece.Attacks(b, pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRayClear - checks whether all squares strictly between from and to (on a line or diagonal) are empty
+func (b *Board) isRayClear(from Position, to Position) bool {
+	stepX, stepY := sign(to.X-from.X), sign(to.Y-from.Y)
+	for x, y := from.X+stepX, from.Y+stepY; x != to.X || y != to.Y; x, y = x+stepX, y+stepY {
+		if b.Grid[y][x] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// InCheck - checks whether the king of the side to move is attacked
+func (s *State) InCheck() bool {
+	kingPos := s.Board.FindPiece("king", s.Turn)
+	if len(kingPos) == 0 {
+		return false
+	}
+	enemy := "white"
+	if s.Turn == "white" {
+		enemy = "black"
+	}
+	return s.Board.IsAttacked(kingPos[0], enemy)
+}
@@ -27,6 +27,7 @@ type Board struct {
 	squaresControlledCache map[string]([]*Position)
 	kingsPositionCache     map[string]*Position
 	isCopy                 bool
+	accumulator            Accumulator
 }
 
 // createBoard - creates a new board with the given setup
@@ -43,6 +44,7 @@ func createBoard(setup string) (result *Board, err error) {
 			"black": nil,
 		},
 		false,
+		nil,
 	}
 	err = result.initBoard(setup)
 	return
@@ -256,7 +258,11 @@ func (b *Board) PlaceOn(piece *Piece, pos *Position) bool {
 	if !b.isInBounds(pos) {
 		return false
 	}
+	b.RemoveFrom(pos)
 	b.Grid[pos.Y][pos.X] = piece
+	if b.accumulator != nil && piece != nil {
+		b.accumulator.Add(piece, *pos)
+	}
 	return true
 }
 
@@ -270,7 +276,7 @@ func (b *Board) placeNew(color string, typ string, pos Position) (*Piece, error)
 	if err != nil {
 		return nil, fmt.Errorf("creation of piece failed")
 	}
-	b.Grid[pos.Y][pos.X] = piece
+	b.PlaceOn(piece, &pos)
 	return piece, nil
 }
 
@@ -279,6 +285,9 @@ func (b *Board) RemoveFrom(pos *Position) {
 	if !b.isInBounds(pos) {
 		return
 	}
+	if b.accumulator != nil && b.Grid[pos.Y][pos.X] != nil {
+		b.accumulator.Remove(b.Grid[pos.Y][pos.X], *pos)
+	}
 	b.Grid[pos.Y][pos.X] = nil
 }
 
@@ -318,5 +327,8 @@ func (b *Board) copy() (*Board, error) {
 		}
 	}
 	copy.isCopy = true
+	if b.accumulator != nil {
+		copy.accumulator = b.accumulator.Copy()
+	}
 	return copy, nil
 }
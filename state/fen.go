@@ -0,0 +1,181 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Forsyth-Edwards Notation
+// en passant isn't supported by the move generator, so the en passant field is ignored when reading
+// and always written as "-", the move counters aren't tracked and are written as "0 1"
+
+// StartFEN - FEN of the default setup
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// fenLetters - FEN letter of each piece type (uppercase for white, lowercase for black)
+var fenLetters = map[string]string{
+	"pawn":   "p",
+	"knight": "n",
+	"bishop": "b",
+	"rook":   "r",
+	"queen":  "q",
+	"king":   "k",
+}
+
+// CreateStateFEN - creates a new game state from a FEN string
+func CreateStateFEN(fen string) (*State, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid fen %q: expected at least placement and turn", fen)
+	}
+
+	s, err := CreateState("clear")
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("invalid fen %q: expected 8 ranks", fen)
+	}
+	for i, rank := range ranks {
+		y := 7 - i
+		x := 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				x += int(char - '0')
+				continue
+			}
+			typ, color := "", "white"
+			for t, letter := range fenLetters {
+				if string(char) == letter {
+					typ, color = t, "black"
+				} else if string(char) == strings.ToUpper(letter) {
+					typ = t
+				}
+			}
+			if typ == "" {
+				return nil, fmt.Errorf("invalid fen %q: unknown piece %q", fen, char)
+			}
+			piece, err := s.Board.placeNew(color, typ, Position{X: x, Y: y})
+			if err != nil {
+				return nil, fmt.Errorf("invalid fen %q: %v", fen, err)
+			}
+			// pawns that left their starting rank have moved, it doesn't matter for other pieces
+			if typ == "pawn" && !((color == "white" && y == 1) || (color == "black" && y == 6)) {
+				piece.HasMoved = true
+			}
+			x++
+		}
+		if x != 8 {
+			return nil, fmt.Errorf("invalid fen %q: rank %v doesn't have 8 squares", fen, y+1)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		s.Turn = "white"
+	case "b":
+		s.Turn = "black"
+	default:
+		return nil, fmt.Errorf("invalid fen %q: unknown turn %q", fen, fields[1])
+	}
+
+	castling := "-"
+	if len(fields) > 2 {
+		castling = fields[2]
+	}
+	s.Board.setCastlingRights(castling)
+	return s, nil
+}
+
+// setCastlingRights - marks kings and rooks as moved when they have lost their castling rights
+func (b *Board) setCastlingRights(castling string) {
+	rights := map[string][]struct {
+		letter string
+		rookX  int
+	}{
+		"white": {{"K", 7}, {"Q", 0}},
+		"black": {{"k", 7}, {"q", 0}},
+	}
+	for color, sides := range rights {
+		y := 0
+		if color == "black" {
+			y = 7
+		}
+		canCastle := false
+		for _, side := range sides {
+			rook := b.Grid[y][side.rookX]
+			if strings.Contains(castling, side.letter) {
+				canCastle = true
+			} else if rook != nil && rook.Type == "rook" {
+				rook.HasMoved = true
+			}
+		}
+		king := b.Grid[y][4]
+		if !canCastle || king == nil || king.Type != "king" {
+			for _, pos := range b.FindPiece("king", color) {
+				if piece, _ := b.GetPiece(pos); piece != nil {
+					piece.HasMoved = true
+				}
+			}
+		}
+	}
+}
+
+// castlingRights - the castling field of the FEN, based on which kings and rooks haven't moved yet
+func (b *Board) castlingRights() (result string) {
+	for _, side := range []struct {
+		letter string
+		color  string
+		rookX  int
+	}{{"K", "white", 7}, {"Q", "white", 0}, {"k", "black", 7}, {"q", "black", 0}} {
+		y := 0
+		if side.color == "black" {
+			y = 7
+		}
+		king, rook := b.Grid[y][4], b.Grid[y][side.rookX]
+		if king != nil && king.Type == "king" && king.Color == side.color && !king.HasMoved &&
+			rook != nil && rook.Type == "rook" && rook.Color == side.color && !rook.HasMoved {
+			result += side.letter
+		}
+	}
+	if result == "" {
+		return "-"
+	}
+	return
+}
+
+// FEN - returns the state as a FEN string
+func (s *State) FEN() string {
+	ranks := []string{}
+	for y := 7; y >= 0; y-- {
+		rank := ""
+		empty := 0
+		for x := range 8 {
+			piece := s.Board.Grid[y][x]
+			if piece == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				rank += fmt.Sprint(empty)
+				empty = 0
+			}
+			if piece.Color == "white" {
+				rank += strings.ToUpper(fenLetters[piece.Type])
+			} else {
+				rank += fenLetters[piece.Type]
+			}
+		}
+		if empty > 0 {
+			rank += fmt.Sprint(empty)
+		}
+		ranks = append(ranks, rank)
+	}
+	turn := "w"
+	if s.Turn == "black" {
+		turn = "b"
+	}
+	return fmt.Sprintf("%v %v %v - 0 1", strings.Join(ranks, "/"), turn, s.Board.castlingRights())
+}
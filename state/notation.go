@@ -0,0 +1,183 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Move notations used by the engine protocols
+
+// promotionLetters - piece type of the promotion letters of coordinate notation
+var promotionLetters = map[string]string{
+	"q": "queen",
+	"r": "rook",
+	"b": "bishop",
+	"n": "knight",
+}
+
+// UCI - the move in coordinate notation as used by UCI and XBoard (e.g. e2e4, e7e8q)
+func (m *Move) UCI() string {
+	result := strings.ToLower(m.From.ToAlgebraic() + m.To.ToAlgebraic())
+	for letter, typ := range promotionLetters {
+		if m.Promotion == typ {
+			result += letter
+		}
+	}
+	return result
+}
+
+// MoveFromUCI - finds the legal move written in coordinate notation
+func (s *State) MoveFromUCI(str string) (*Move, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	if len(str) != 4 && len(str) != 5 {
+		return nil, fmt.Errorf("invalid move %q", str)
+	}
+	promotion := ""
+	if len(str) == 5 {
+		typ, ok := promotionLetters[str[4:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid promotion in move %q", str)
+		}
+		promotion = typ
+	}
+
+	legalMoves, err := s.GetLegalMoves()
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range legalMoves {
+		if move.UCI()[:4] == str[:4] && move.Promotion == promotion {
+			return move, nil
+		}
+	}
+	if promotion != "" && promotion != "queen" {
+		return nil, fmt.Errorf("move %q: only promotions to a queen are supported", str)
+	}
+	return nil, fmt.Errorf("illegal move %q", str)
+}
+
+// sanLetters - piece letters of standard algebraic notation, pawns have none
+var sanLetters = map[string]string{
+	"king":   "K",
+	"queen":  "Q",
+	"rook":   "R",
+	"bishop": "B",
+	"knight": "N",
+	"pawn":   "",
+}
+
+// SAN - the legal move in standard algebraic notation (e.g. Nf3, exd5, O-O, e8=Q#), as used in PGN
+func (s *State) SAN(move *Move) (string, error) {
+	piece, err := s.Board.GetPiece(&move.From)
+	if err != nil {
+		return "", err
+	}
+	if piece == nil {
+		return "", fmt.Errorf("no piece on %v", move.From.ToAlgebraic())
+	}
+	target, err := s.Board.GetPiece(&move.To)
+	if err != nil {
+		return "", err
+	}
+	to := strings.ToLower(move.To.ToAlgebraic())
+
+	var result string
+	switch {
+	case piece.Type == "king" && move.To.X-move.From.X == 2:
+		result = "O-O"
+	case piece.Type == "king" && move.From.X-move.To.X == 2:
+		result = "O-O-O"
+	case piece.Type == "pawn":
+		if target != nil {
+			result = strings.ToLower(algebraicLetters[move.From.X]) + "x"
+		}
+		result += to
+		if move.Promotion != "" {
+			result += "=" + sanLetters[move.Promotion]
+		}
+	default:
+		disambiguation, err := s.sanDisambiguation(piece, move)
+		if err != nil {
+			return "", err
+		}
+		result = sanLetters[piece.Type] + disambiguation
+		if target != nil {
+			result += "x"
+		}
+		result += to
+	}
+
+	// check and mate are marked after the move
+	next, err := s.Copy()
+	if err != nil {
+		return "", err
+	}
+	if _, err := next.ApplyMove(move); err != nil {
+		return "", err
+	}
+	if next.InCheck() {
+		isMate, err := next.IsCheckmate()
+		if err != nil {
+			return "", err
+		}
+		if isMate {
+			return result + "#", nil
+		}
+		return result + "+", nil
+	}
+	return result, nil
+}
+
+// sanDisambiguation - the file, rank or square of the moving piece, needed when another piece
+// of the same type can move to the same square
+func (s *State) sanDisambiguation(piece *Piece, move *Move) (string, error) {
+	legalMoves, err := s.GetLegalMoves()
+	if err != nil {
+		return "", err
+	}
+	sameFile, sameRank, ambiguous := false, false, false
+	for _, other := range legalMoves {
+		if other.From.Equal(move.From) || !other.To.Equal(move.To) {
+			continue
+		}
+		otherPiece, _ := s.Board.GetPiece(&other.From)
+		if otherPiece == nil || otherPiece.Type != piece.Type {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From.X == move.From.X
+		sameRank = sameRank || other.From.Y == move.From.Y
+	}
+	from := strings.ToLower(move.From.ToAlgebraic())
+	switch {
+	case !ambiguous:
+		return "", nil
+	case !sameFile:
+		return from[:1], nil
+	case !sameRank:
+		return from[1:], nil
+	default:
+		return from, nil
+	}
+}
+
+// MoveFromSAN - finds the legal move written in standard algebraic notation
+// check and mate markers and annotations (!, ?) are optional
+func (s *State) MoveFromSAN(str string) (*Move, error) {
+	wanted := strings.TrimRight(strings.TrimSpace(str), "+#!?")
+	wanted = strings.ReplaceAll(wanted, "0", "O")
+	legalMoves, err := s.GetLegalMoves()
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range legalMoves {
+		san, err := s.SAN(move)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimRight(san, "+#") == wanted {
+			return move, nil
+		}
+	}
+	return nil, fmt.Errorf("illegal move %q", str)
+}
@@ -0,0 +1,32 @@
+package state
+
+// Perft - the number of move sequences of the given length from this position, used to check the move generator
+// the counts differ from the published ones in positions with en passant or underpromotions, which aren't supported
+func (s *State) Perft(depth int) (int, error) {
+	if depth <= 0 {
+		return 1, nil
+	}
+	legalMoves, err := s.GetLegalMoves()
+	if err != nil {
+		return 0, err
+	}
+	if depth == 1 {
+		return len(legalMoves), nil
+	}
+	nodes := 0
+	for _, move := range legalMoves {
+		next, err := s.Copy()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := next.ApplyMove(move); err != nil {
+			return 0, err
+		}
+		n, err := next.Perft(depth - 1)
+		if err != nil {
+			return 0, err
+		}
+		nodes += n
+	}
+	return nodes, nil
+}
@@ -276,6 +276,22 @@ func (s *State) IsStalemate() (bool, error) {
 	return false, nil
 }
 
+// IsInsufficientMaterial - checks whether neither side has enough material left to ever deliver mate
+// that is only the kings, or the kings and a single bishop or knight
+func (s *State) IsInsufficientMaterial() bool {
+	minors := 0
+	for _, piece := range s.Board.GetPieces() {
+		switch piece.Type {
+		case "king":
+		case "bishop", "knight":
+			minors++
+		default:
+			return false
+		}
+	}
+	return minors <= 1
+}
+
 // Copy - makes a deep copy of the state
 // deepcopy -> all pieces on the board most be copied and so on
 func (s *State) Copy() (*State, error) {
@@ -293,6 +309,17 @@ func (s *State) Copy() (*State, error) {
 	}, nil
 }
 
+// NullMove - returns a copy of the state where the side to move passes its turn
+// only meant for the search (null move pruning), passing is not a legal move
+func (s *State) NullMove() (*State, error) {
+	next, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	next.switchTurn()
+	return next, nil
+}
+
 // some helper functions
 func (s *State) Equal(other *State) bool {
 	return s.Board.Equal(other.Board) && s.Turn == other.Turn
@@ -227,3 +227,117 @@ func TestPromotion(t *testing.T) {
 		t.Error("Pawn should have promoted to queen")
 	}
 }
+
+func TestFEN(t *testing.T) {
+	s, err := CreateState("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FEN() != StartFEN {
+		t.Errorf("Expected %v, got %v", StartFEN, s.FEN())
+	}
+
+	fen := "r3k2r/pp3ppp/8/8/8/8/PP3PPP/R3K2R b Kq - 0 1"
+	s, err = CreateStateFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FEN() != fen {
+		t.Errorf("Expected %v, got %v", fen, s.FEN())
+	}
+	if s.Turn != "black" {
+		t.Errorf("Expected black to move")
+	}
+
+	if _, err := CreateStateFEN("rnbqkbnr/pppppppp/8/8 w"); err == nil {
+		t.Errorf("Expected an error for an incomplete fen")
+	}
+}
+
+func TestAttackersOf(t *testing.T) {
+	s, err := CreateStateFEN("4k3/8/8/3p4/8/5N2/8/R3K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d4 is attacked by the knight on f3 and the pawn on d5 doesn't attack straight ahead
+	if n := len(s.Board.AttackersOf(&Position{X: 3, Y: 3}, "white")); n != 1 {
+		t.Errorf("Expected 1 white attacker on d4, got %d", n)
+	}
+	if s.Board.IsAttacked(&Position{X: 3, Y: 3}, "black") {
+		t.Errorf("Expected d4 not to be attacked by black")
+	}
+	// the rook on a1 attacks up the a-file, but its way along the first rank is blocked by the king
+	if !s.Board.IsAttacked(&Position{X: 0, Y: 6}, "white") {
+		t.Errorf("Expected a7 to be attacked by the rook")
+	}
+	if s.Board.IsAttacked(&Position{X: 7, Y: 0}, "white") {
+		t.Errorf("Expected h1 not to be attacked")
+	}
+}
+
+func TestNotation(t *testing.T) {
+	// knights and rooks that can reach the same squares, castling and a promotion with check
+	s, err := CreateStateFEN("3k4/1P6/8/1N3N2/8/R6R/8/R3K3 w Q - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"b7b8q": "b8=Q+",
+		"b5d4":  "Nbd4",
+		"f5d4":  "Nfd4",
+		"f5e7":  "Ne7",
+		"a3a2":  "R3a2",
+		"a1a2":  "R1a2",
+		"a3d3":  "Rad3+",
+		"h3d3":  "Rhd3+",
+		"e1c1":  "O-O-O+",
+	}
+	for uci, want := range cases {
+		move, err := s.MoveFromUCI(uci)
+		if err != nil {
+			t.Fatalf("%v: %v", uci, err)
+		}
+		if move.UCI() != uci {
+			t.Errorf("Expected %v, got %v", uci, move.UCI())
+		}
+		san, err := s.SAN(move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if san != want {
+			t.Errorf("%v: expected %v, got %v", uci, want, san)
+		}
+		back, err := s.MoveFromSAN(san)
+		if err != nil || back != move {
+			t.Errorf("%v: %v did not parse back to the same move (%v)", uci, san, err)
+		}
+	}
+
+	mate, err := CreateStateFEN("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	move, err := mate.MoveFromSAN("Rd8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if san, _ := mate.SAN(move); san != "Rd8#" || move.UCI() != "d1d8" {
+		t.Errorf("Expected Rd8# (d1d8), got %v (%v)", san, move.UCI())
+	}
+}
+
+func TestPerft(t *testing.T) {
+	s, err := CreateStateFEN(StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for depth, expected := range []int{1, 20, 400, 8902} {
+		nodes, err := s.Perft(depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes != expected {
+			t.Errorf("Expected %v nodes at depth %v, got %v", expected, depth, nodes)
+		}
+	}
+}
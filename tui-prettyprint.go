@@ -3,8 +3,11 @@ package main
 import (
 	//"fmt"
 	"fmt"
+	"time"
 
 	color "github.com/fatih/color"
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
 	chess "github.com/spunker/chess/state"
 )
 
@@ -66,7 +69,7 @@ func printLine(rank []*chess.Piece, white bool, number int, selected int, cursor
 	return
 }
 
-func printRank(rank []*chess.Piece, white bool, number int, sel []chess.Position, cursor chess.Position, statistic string) (result string) {
+func printRank(rank []*chess.Piece, white bool, number int, sel []chess.Position, cursor chess.Position, statistics [3]string) (result string) {
 	var selected int
 	if len(sel) == 1 && sel[0].Y == number-1 {
 		selected = sel[0].X
@@ -92,17 +95,17 @@ func printRank(rank []*chess.Piece, white bool, number int, sel []chess.Position
 	emptyrank := []*chess.Piece{nil, nil, nil, nil, nil, nil, nil, nil}
 
 	//first line
-	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + "\n"
+	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + fmt.Sprintln(statistics[0])
 
 	//second line
-	result += spacingBefore + printLine(rank, white, number, selected, cursored) + fmt.Sprintln(statistic)
+	result += spacingBefore + printLine(rank, white, number, selected, cursored) + fmt.Sprintln(statistics[1])
 
 	//third line
-	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + "\n"
+	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + fmt.Sprintln(statistics[2])
 	return
 }
 
-func printRankReverse(rank []*chess.Piece, white bool, number int, sel []chess.Position, cursor chess.Position, statistic string) (result string) {
+func printRankReverse(rank []*chess.Piece, white bool, number int, sel []chess.Position, cursor chess.Position, statistics [3]string) (result string) {
 	var selected int
 	if len(sel) == 1 && sel[0].Y == number-1 {
 		selected = sel[0].X
@@ -128,21 +131,44 @@ func printRankReverse(rank []*chess.Piece, white bool, number int, sel []chess.P
 	emptyrank := []*chess.Piece{nil, nil, nil, nil, nil, nil, nil, nil}
 
 	//first line
-	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + "\n"
+	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + fmt.Sprintln(statistics[0])
 
 	//second line
-	result += spacingBefore + printLine(rank, white, number, selected, cursored) + fmt.Sprintln(statistic)
+	result += spacingBefore + printLine(rank, white, number, selected, cursored) + fmt.Sprintln(statistics[1])
 
 	//third line
-	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + "\n"
+	result += spacingBefore + printLine(emptyrank, white, -1, selected, cursored) + fmt.Sprintln(statistics[2])
 	return
 }
 
-func (m model) boardView() (result string) {
-	if m.menu.playerColor == "black" {
-		return m.boardViewBlack()
+// botEvalString - the score of the last bot search, or the distance to mate if it found one
+func botEvalString() string {
+	if botResult == nil {
+		return ""
 	}
+	return botResult.ScoreString()
+}
+
+// botLineString - the principal variation of the last bot search
+func botLineString() string {
+	if botResult == nil {
+		return ""
+	}
+	return botResult.PVString()
+}
 
+// botSearchString - depth, nodes and speed of the last bot search
+func botSearchString() string {
+	if botResult == nil {
+		return ""
+	}
+	return fmt.Sprintf("depth %v/%v, %v nodes in %v (%v nps)",
+		botResult.Depth, botResult.SelDepth, botResult.Nodes, botResult.Time.Round(time.Millisecond), botResult.NPS)
+}
+
+// sidePanel - the text next to the board, three lines for every rank from top to bottom
+// normally one statistic per rank, with the evaluation breakdown toggled on everything is packed together
+func (m model) sidePanel() (result [8][3]string) {
 	var lastMoveString string
 	if len(m.game.State.PreviousMoves) > 0 {
 		lastMoveString = m.game.State.PreviousMoves[len(m.game.State.PreviousMoves)-1].ToAlgebraic()
@@ -150,17 +176,61 @@ func (m model) boardView() (result string) {
 		lastMoveString = ""
 	}
 
+	stats := []string{
+		fmt.Sprintf("       advantage for white: %v", game.GetMaterialStats(m.game.State.Board).GetAdvantage("white")),
+		fmt.Sprintf("       bot evaluation:      %v", botEvalString()),
+		fmt.Sprintf("       to move:             %v", m.game.State.Turn),
+		fmt.Sprintf("       last move:           %v", lastMoveString),
+		fmt.Sprintf("       bot line:            %v", botLineString()),
+		fmt.Sprintf("       bot search:          %v", botSearchString()),
+	}
+	if !m.explain {
+		for i, stat := range stats {
+			result[i][1] = stat
+		}
+		return
+	}
+
+	lines := append(stats, "")
+	lines = append(lines, m.explainLines()...)
+	for i, line := range lines {
+		if i < 8*3 {
+			result[i/3][i%3] = line
+		}
+	}
+	return
+}
+
+// explainLines - the evaluation breakdown of the current position as a table (toggle with e)
+func (m model) explainLines() (result []string) {
+	explanation, err := ai.Explain(m.game.State, &m.menu.weights)
+	if err != nil {
+		return []string{fmt.Sprintf("       %v", err)}
+	}
+	result = append(result, fmt.Sprintf("       %-22v %7v %7v %7v %7v", "term (toggle with e)", "white", "black", "weight", "total"))
+	total := 0.0
+	for _, term := range explanation {
+		result = append(result, fmt.Sprintf("       %-22v %7.2f %7.2f %7.2f %7.2f",
+			term.Name, term.Raw["white"], term.Raw["black"], term.Weight, term.Total))
+		total += term.Total
+	}
+	result = append(result, fmt.Sprintf("       %-22v %31.2f", "evaluation", total))
+	return
+}
+
+func (m model) boardView() (result string) {
+	if m.menu.playerColor == "black" {
+		return m.boardViewBlack()
+	}
+	panel := m.sidePanel()
+
 	result += "\n"
 	result += spacingBefore + greenSquare.Sprintln("        A      B      C      D      E      F      G      H        ")
 	result += spacingBefore + greenSquare.Sprintln("                                                                  ")
-	result += printRank(m.game.State.Board.Grid[7], false, 8, m.selected, m.cursor, fmt.Sprintf("       advantage for white: %v", GetMaterialStats(m.game.State.Board).GetAdvantage("white")))
-	result += printRank(m.game.State.Board.Grid[6], true, 7, m.selected, m.cursor, fmt.Sprintf("       bot evaluation:      %v", botEvaln))
-	result += printRank(m.game.State.Board.Grid[5], false, 6, m.selected, m.cursor, fmt.Sprintf("       to move:             %v", m.game.State.Turn))
-	result += printRank(m.game.State.Board.Grid[4], true, 5, m.selected, m.cursor, fmt.Sprintf("       last move:           %v", lastMoveString))
-	result += printRank(m.game.State.Board.Grid[3], false, 4, m.selected, m.cursor, "")
-	result += printRank(m.game.State.Board.Grid[2], true, 3, m.selected, m.cursor, "")
-	result += printRank(m.game.State.Board.Grid[1], false, 2, m.selected, m.cursor, "")
-	result += printRank(m.game.State.Board.Grid[0], true, 1, m.selected, m.cursor, "")
+	for i := range 8 {
+		rank := 7 - i
+		result += printRank(m.game.State.Board.Grid[rank], i%2 == 1, rank+1, m.selected, m.cursor, panel[i])
+	}
 	result += spacingBefore + greenSquare.Sprintln("                                                                  ")
 	result += spacingBefore + greenSquare.Sprintln("        A      B      C      D      E      F      G      H        ")
 	result += "\n"
@@ -168,25 +238,53 @@ func (m model) boardView() (result string) {
 }
 
 func (m model) boardViewBlack() (result string) {
-	var lastMoveString string
-	if len(m.game.State.PreviousMoves) > 0 {
-		lastMoveString = m.game.State.PreviousMoves[len(m.game.State.PreviousMoves)-1].ToAlgebraic()
-	} else {
-		lastMoveString = ""
-	}
+	panel := m.sidePanel()
+
 	result += "\n"
 	result += spacingBefore + greenSquare.Sprintln("        A      B      C      D      E      F      G      H        ")
 	result += spacingBefore + greenSquare.Sprintln("                                                                  ")
-	result += printRankReverse(m.game.State.Board.Grid[0], false, 1, m.selected, m.cursor, fmt.Sprintf("       advantage for white: %v", GetMaterialStats(m.game.State.Board).GetAdvantage("white")))
-	result += printRankReverse(m.game.State.Board.Grid[1], true, 2, m.selected, m.cursor, fmt.Sprintf("       bot evaluation:      %v", botEvaln))
-	result += printRankReverse(m.game.State.Board.Grid[2], false, 3, m.selected, m.cursor, fmt.Sprintf("       to move:             %v", m.game.State.Turn))
-	result += printRankReverse(m.game.State.Board.Grid[3], true, 4, m.selected, m.cursor, fmt.Sprintf("       last move:           %v", lastMoveString))
-	result += printRankReverse(m.game.State.Board.Grid[4], false, 5, m.selected, m.cursor, "")
-	result += printRankReverse(m.game.State.Board.Grid[5], true, 6, m.selected, m.cursor, "")
-	result += printRankReverse(m.game.State.Board.Grid[6], false, 7, m.selected, m.cursor, "")
-	result += printRankReverse(m.game.State.Board.Grid[7], true, 8, m.selected, m.cursor, "")
+	for i := range 8 {
+		result += printRankReverse(m.game.State.Board.Grid[i], i%2 == 1, i+1, m.selected, m.cursor, panel[i])
+	}
 	result += spacingBefore + greenSquare.Sprintln("                                                                  ")
 	result += spacingBefore + greenSquare.Sprintln("        A      B      C      D      E      F      G      H        ")
 	result += "\n"
 	return result
 }
+
+// analysisView - the analysis panel below the board, listing the top lines for the current position
+func (m model) analysisView() (result string) {
+	if !m.analysis {
+		return ""
+	}
+	result += spacingBefore + "analysis (toggle with a)\n"
+	if m.lines == nil {
+		result += spacingBefore + "  thinking...\n"
+		return
+	}
+	for i, line := range m.lines {
+		result += spacingBefore + fmt.Sprintf("  %v. %-8v %v\n", i+1, line.ScoreString(), line.PVString())
+	}
+	return
+}
+
+// gameOverView - the result below the board once the game is over, or the draw the player may claim
+func (m model) gameOverView() (result string) {
+	if !m.game.Over {
+		if claim := m.game.Claimable(); claim != "" {
+			result += spacingBefore + fmt.Sprintf("Draw by %v can be claimed: d to claim it\n\n", claim)
+		}
+		return
+	}
+	outcome, reason := m.game.Result()
+	verdict := "Draw"
+	switch m.game.Loser {
+	case m.menu.playerColor:
+		verdict = color.RedString("You lose")
+	case "white", "black":
+		verdict = color.GreenString("You win")
+	}
+	result += spacingBefore + fmt.Sprintf("Game over: %v (%v) - %v\n", outcome, reason, verdict)
+	result += spacingBefore + "u to take back, n for a new game, q to quit\n\n"
+	return
+}
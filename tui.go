@@ -1,77 +1,183 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 
 	tea "github.com/charmbracelet/bubbletea"
 	color "github.com/fatih/color"
 
 	//color "github.com/fatih/color"
 	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
 	chess "github.com/spunker/chess/state"
 )
 
-var botEvaln float64
+var botResult *ai.SearchResult
+
+// analysisLines - number of lines shown in the analysis panel
+const analysisLines = 3
 
 type Menu struct {
 	playerColor string
 	setup       string
+	fen         string // position to start from instead of the setup, "" for the setup
 	botDepth    int
+	skill       int             // skill level of the bot, -1 plays at full strength with botDepth
+	rng         *rand.Rand      // picks between moves when playing with a skill level
+	external    *externalEngine // UCI engine playing instead of our bot, nil for our own
+	botThreads  int
 	weights     ai.Weights
+	profile     string // name (or path) of the profile the weights were loaded from
+	status      string // result of the last profile action, shown below the menu
 }
 
 type model struct {
-	game       *Game
+	game       *game.Game
 	cursor     chess.Position
 	selected   []chess.Position
 	inMenu     bool
 	menu       Menu
 	menuCursor int
+	explain    bool               // evaluation breakdown toggled on
+	analysis   bool               // analysis panel toggled on
+	lines      []*ai.SearchResult // top lines for the current position (analysis panel)
 }
 
-func initialModel() model {
+func initialModel(opts TuiOptions, weights ai.Weights, external *externalEngine) model {
 	return model{
 		inMenu: true,
 		menu: Menu{
-			playerColor: "white",
-			setup:       "default",
-			botDepth:    3,
-			weights: ai.Weights{
-				Material: 2.0,
-				Mobility: 0.5,
-			},
+			playerColor: opts.Color,
+			setup:       opts.Setup,
+			fen:         opts.FEN,
+			botDepth:    opts.Depth,
+			skill:       opts.Skill,
+			rng:         ai.NewSkillRand(opts.Seed),
+			external:    external,
+			botThreads:  runtime.NumCPU(),
+			weights:     weights,
+			profile:     opts.Profile,
 		},
 		menuCursor: 0,
 	}
 }
 
+// cycleProfile - selects the previous (step -1) or next (step 1) profile and loads its weights
+func (m *Menu) cycleProfile(step int) {
+	profiles := ai.ListProfiles()
+	index := slices.Index(profiles, m.profile)
+	if index < 0 { // loaded by path, or deleted in the meantime
+		index = 0
+	} else {
+		index = (index + step + len(profiles)) % len(profiles)
+	}
+	weights, err := ai.LoadProfile(profiles[index])
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.profile = profiles[index]
+	m.weights = *weights
+	m.status = ""
+}
+
+// saveProfile - saves the current weights under the selected profile (as "custom" when that is the built in one)
+func (m *Menu) saveProfile() {
+	name := m.profile
+	if name == ai.DefaultProfile || filepath.Ext(name) != "" {
+		name = "custom"
+	}
+	path, err := ai.SaveProfile(name, &m.weights)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.profile = name
+	m.status = fmt.Sprintf("saved to %v", path)
+}
+
 func (m model) Init() tea.Cmd {
 	return nil
 }
 
 type BotMoveMsg struct {
-	move  *chess.Move
-	score float64
+	ply    int // number of moves played when the search started, a move for another position is dropped
+	move   *chess.Move
+	result *ai.SearchResult
 }
 
-func (m model) getBotMove(s *chess.State, depth int) tea.Cmd {
-	// Wait for 100 ms before returning
+// getBotMove - searches a copy of the position, the game may change (take backs, a new game) while the bot thinks
+func (m model) getBotMove(position *chess.State, depth int) tea.Cmd {
+	s, err := position.Copy()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return nil
+	}
+	ply := len(position.PreviousMoves)
 	return func() tea.Msg {
-		move, score, err := ai.SelectMove(s, depth, &m.menu.weights)
+		var res *ai.SearchResult
+		var err error
+		if m.menu.external != nil {
+			var move *chess.Move
+			move, err = m.menu.external.bestMove(s, searchLimits{depth: depth})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			return BotMoveMsg{ply: ply, move: move}
+		}
+		if m.menu.skill >= 0 {
+			res, err = ai.SearchSkill(s, &m.menu.weights, ai.SkillLevels[m.menu.skill], m.menu.botThreads, m.menu.rng)
+		} else {
+			res, err = ai.Search(s, &m.menu.weights, &ai.SearchOptions{Depth: depth, Threads: m.menu.botThreads})
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
 		result := BotMoveMsg{
-			move:  move,
-			score: score,
+			ply:    ply,
+			result: res,
+		}
+		if res != nil {
+			result.move = res.Move
 		}
 		//fmt.Printf("result botmovemsg: %v", result.move.ToAlgebraic())
 		return result
 	}
 }
 
+type AnalysisMsg struct {
+	ply   int // number of moves played when the analysis started, used to drop stale results
+	lines []*ai.SearchResult
+}
+
+// getAnalysis - runs a multi-PV search on a copy of the current position
+func (m model) getAnalysis() tea.Cmd {
+	s, err := m.game.State.Copy()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return nil
+	}
+	ply := len(m.game.State.PreviousMoves)
+	depth := max(m.menu.botDepth, 1)
+	return func() tea.Msg {
+		lines, err := ai.SearchMultiPV(s, &m.menu.weights, &ai.SearchOptions{Depth: depth, Threads: m.menu.botThreads}, analysisLines)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		return AnalysisMsg{
+			ply:   ply,
+			lines: lines,
+		}
+	}
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.inMenu {
 		switch msg := msg.(type) {
@@ -94,6 +200,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case 1: // setup
 					choices := []string{"default", "castling", "promotion", "clear"}
 					if m.game == nil {
+						m.menu.fen = ""
 						for i, setup := range choices {
 							if setup == m.menu.setup {
 								if i == 0 {
@@ -106,17 +213,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 				case 2: // botDepth
-					if m.menu.botDepth > 0 {
+					if m.menu.botDepth > 1 { // the search always looks at least one ply ahead
 						m.menu.botDepth--
 					}
 
-				case 3: // weight material
+				case 3: // skill
+					if m.menu.skill > -1 {
+						m.menu.skill--
+					}
+
+				case 4: // botThreads
+					if m.menu.botThreads > 1 {
+						m.menu.botThreads--
+					}
+
+				case 5: // weight material
 					m.menu.weights.Material -= 0.1
 					m.menu.weights.Material = math.Round(m.menu.weights.Material*10) / 10
 
-				case 4: // weight mobility
+				case 6: // weight mobility
 					m.menu.weights.Mobility -= 0.1
 					m.menu.weights.Mobility = math.Round(m.menu.weights.Mobility*10) / 10
+
+				case 7: // weight piece-square
+					m.menu.weights.PieceSquare -= 0.1
+					m.menu.weights.PieceSquare = math.Round(m.menu.weights.PieceSquare*10) / 10
+
+				case 8: // profile
+					m.menu.cycleProfile(-1)
 				}
 
 			case "right", "l":
@@ -131,6 +255,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case 1: // setup
 					choices := []string{"default", "castling", "promotion", "clear"}
 					if m.game == nil {
+						m.menu.fen = ""
 						for i, setup := range choices {
 							if setup == m.menu.setup {
 								if i == len(choices)-1 {
@@ -147,22 +272,42 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.menu.botDepth++
 					}
 
-				case 3: // weight material
+				case 3: // skill
+					if m.menu.skill < ai.MaxSkill {
+						m.menu.skill++
+					}
+
+				case 4: // botThreads
+					if m.menu.botThreads < runtime.NumCPU() {
+						m.menu.botThreads++
+					}
+
+				case 5: // weight material
 					m.menu.weights.Material += 0.1
 					m.menu.weights.Material = math.Round(m.menu.weights.Material*10) / 10
 
-				case 4: // weight mobility
+				case 6: // weight mobility
 					m.menu.weights.Mobility += 0.1
 					m.menu.weights.Mobility = math.Round(m.menu.weights.Mobility*10) / 10
+
+				case 7: // weight piece-square
+					m.menu.weights.PieceSquare += 0.1
+					m.menu.weights.PieceSquare = math.Round(m.menu.weights.PieceSquare*10) / 10
+
+				case 8: // profile
+					m.menu.cycleProfile(1)
 				}
 
+			case "s":
+				m.menu.saveProfile()
+
 			case "up", "k":
 				if m.menuCursor > 0 {
 					m.menuCursor--
 				}
 
 			case "down", "j":
-				if m.menuCursor < 4 {
+				if m.menuCursor < 8 {
 					m.menuCursor++
 				}
 
@@ -170,16 +315,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.inMenu = false
 				if m.game == nil {
 					var err error
-					m.game, err = StartGame(m.menu.setup)
+					if m.menu.fen != "" {
+						m.game, err = game.StartGameFEN(m.menu.fen)
+					} else {
+						m.game, err = game.StartGame(m.menu.setup)
+					}
 					if err != nil {
 						fmt.Println(err)
+						return m, tea.Quit
 					}
 					m.cursor = chess.Position{
 						X: 4,
 						Y: 4,
 					}
 				}
-				if m.menu.playerColor == "black" {
+				if m.game.State.Turn != m.menu.playerColor && !m.game.Over {
 					return m, m.getBotMove(m.game.State, m.menu.botDepth)
 				}
 			}
@@ -230,8 +380,43 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "i":
 				m.inMenu = true
 
+			case "e":
+				m.explain = !m.explain
+
+			case "r":
+				if err := m.game.Resign(m.menu.playerColor); err == nil {
+					m.selected = []chess.Position{}
+				}
+
+			case "d":
+				if err := m.game.ClaimDraw(); err == nil {
+					m.selected = []chess.Position{}
+				}
+
+			case "u":
+				return m, m.takeBack(m.game.Undo)
+
+			case "y":
+				return m, m.takeBack(m.game.Redo)
+
+			case "n":
+				if m.game.Over {
+					m.game = nil
+					m.inMenu = true
+					m.selected = []chess.Position{}
+					m.lines = nil
+					botResult = nil
+				}
+
+			case "a":
+				m.analysis = !m.analysis
+				m.lines = nil
+				if m.analysis {
+					return m, m.getAnalysis()
+				}
+
 			case "enter", " ":
-				if m.game.State.Turn == m.menu.playerColor {
+				if m.game.State.Turn == m.menu.playerColor && !m.game.Over {
 					m.selected = append(m.selected, m.cursor)
 					if len(m.selected) >= 2 {
 						ok, err := m.game.PlayMove(&chess.Move{
@@ -243,6 +428,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 						m.selected = []chess.Position{}
 						if ok {
+							m.lines = nil
+							if m.game.Over {
+								return m, nil
+							}
 							return m, m.getBotMove(m.game.State, m.menu.botDepth)
 						}
 					}
@@ -251,19 +440,70 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case BotMoveMsg:
-			m.game.PlayMove(msg.move)
-			botEvaln = msg.score
+			if msg.move == nil || m.game == nil || m.game.Over || msg.ply != len(m.game.State.PreviousMoves) {
+				return m, nil
+			}
+			ok, err := m.game.PlayMove(msg.move)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			if !ok {
+				return m, nil
+			}
+			botResult = msg.result
+			if m.analysis {
+				m.lines = nil
+				return m, m.getAnalysis()
+			}
+			return m, nil
+
+		case AnalysisMsg:
+			if m.analysis && msg.ply == len(m.game.State.PreviousMoves) {
+				m.lines = msg.lines
+			}
 			return m, nil
 		}
 	}
 	return m, nil
 }
 
+// takeBack - undoes (or redoes) moves until it is the player's turn again, a full move pair against the bot
+// only while the bot isn't thinking, its move would land in the wrong position
+func (m *model) takeBack(step func() (bool, error)) tea.Cmd {
+	if m.game.State.Turn != m.menu.playerColor && !m.game.Over {
+		return nil
+	}
+	m.selected = []chess.Position{}
+	for {
+		ok, err := step()
+		if errors.Is(err, game.ErrGameOver) {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+		if !ok || m.game.State.Turn == m.menu.playerColor {
+			break
+		}
+	}
+	botResult = nil
+	m.lines = nil
+	if m.game.State.Turn != m.menu.playerColor && !m.game.Over {
+		// nothing left to redo for the bot, it plays on
+		return m.getBotMove(m.game.State, m.menu.botDepth)
+	}
+	if m.analysis {
+		return m.getAnalysis()
+	}
+	return nil
+}
+
 func (m model) View() string {
 	if m.inMenu {
 		return m.menuView()
 	}
-	return m.boardView()
+	return m.boardView() + m.gameOverView() + m.analysisView()
 }
 
 func (m model) getCursorString() (result map[string]string) {
@@ -271,8 +511,12 @@ func (m model) getCursorString() (result map[string]string) {
 		"playerColor":     "  ",
 		"setup":           "  ",
 		"botDepth":        "  ",
+		"skill":           "  ",
+		"botThreads":      "  ",
 		"materialWeights": "  ",
 		"mobilityWeights": "  ",
+		"pieceSqWeights":  "  ",
+		"profile":         "  ",
 	}
 	switch m.menuCursor {
 	case 0:
@@ -282,9 +526,17 @@ func (m model) getCursorString() (result map[string]string) {
 	case 2:
 		result["botDepth"] = " >"
 	case 3:
-		result["materialWeights"] = " >"
+		result["skill"] = " >"
 	case 4:
+		result["botThreads"] = " >"
+	case 5:
+		result["materialWeights"] = " >"
+	case 6:
 		result["mobilityWeights"] = " >"
+	case 7:
+		result["pieceSqWeights"] = " >"
+	case 8:
+		result["profile"] = " >"
 	}
 	return
 }
@@ -297,17 +549,33 @@ func (m model) menuView() (result string) {
 	result += fmt.Sprintf("%v   You play as:         < %v >\n", cursorString["playerColor"], m.menu.playerColor)
 
 	result += "\n"
-	result += fmt.Sprintf("%v   Setup:               < %v >\n", cursorString["setup"], m.menu.setup)
+	if m.menu.fen != "" {
+		result += fmt.Sprintf("%v   Setup:               < fen > 	%v\n", cursorString["setup"], m.menu.fen)
+	} else {
+		result += fmt.Sprintf("%v   Setup:               < %v >\n", cursorString["setup"], m.menu.setup)
+	}
 
 	result += "\n"
-	if m.menu.botDepth == 0 {
-		result += fmt.Sprintf("%v   Engine depth:        < disabled >\n", cursorString["botDepth"])
-	} else if m.menu.botDepth >= 5 {
+	if m.menu.botDepth >= 5 {
 		result += fmt.Sprintf("%v   Engine depth:        < %v > 	%v\n", cursorString["botDepth"], m.menu.botDepth, color.RedString("(not recommended)"))
 	} else {
 		result += fmt.Sprintf("%v   Engine depth:        < %v > \n", cursorString["botDepth"], m.menu.botDepth)
 	}
 
+	result += "\n"
+	if m.menu.external != nil {
+		result += fmt.Sprintf("     Opponent:            %v\n", m.menu.external.name)
+		result += "\n"
+	}
+	if m.menu.skill < 0 {
+		result += fmt.Sprintf("%v   Engine skill:        < full > \n", cursorString["skill"])
+	} else {
+		result += fmt.Sprintf("%v   Engine skill:        < %v > 	(depth %v)\n", cursorString["skill"], m.menu.skill, ai.SkillLevels[m.menu.skill].Depth)
+	}
+
+	result += "\n"
+	result += fmt.Sprintf("%v   Engine threads:      < %v > \n", cursorString["botThreads"], m.menu.botThreads)
+
 	result += "\n"
 
 	result += "   Weights for bot\n"
@@ -316,11 +584,49 @@ func (m model) menuView() (result string) {
 	result += fmt.Sprintf("%v   material:            < %v > \n", cursorString["materialWeights"], m.menu.weights.Material)
 	result += "\n"
 	result += fmt.Sprintf("%v   mobility:            < %v > \n", cursorString["mobilityWeights"], m.menu.weights.Mobility)
+	result += "\n"
+	result += fmt.Sprintf("%v   piece-square:        < %v > \n", cursorString["pieceSqWeights"], m.menu.weights.PieceSquare)
+	result += "\n"
+	result += fmt.Sprintf("%v   profile:             < %v > 	(s to save)\n", cursorString["profile"], m.menu.profile)
+	if m.menu.status != "" {
+		result += "\n"
+		result += fmt.Sprintf("     %v\n", m.menu.status)
+	}
 	return
 }
 
-func StartTui() {
-	p := tea.NewProgram(initialModel())
+// TuiOptions - how the TUI starts, everything but the engine can still be changed in the menu
+type TuiOptions struct {
+	Profile    string // weights profile of the bot, a name or a path, "" for the default
+	Color      string // color the player starts with
+	Setup      string // setup of the board
+	FEN        string // position to start from instead of the setup
+	Depth      int    // search depth of the bot
+	Skill      int    // skill level of the bot, -1 for full strength
+	Seed       uint64 // makes the choices of a skill level reproducible
+	EnginePath string // UCI engine binary to play against instead of the bot, "" for the bot
+}
+
+// StartTui - starts the TUI
+func StartTui(opts TuiOptions) {
+	weights, err := ai.LoadProfile(opts.Profile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.Profile == "" {
+		opts.Profile = ai.DefaultProfile
+	}
+	var external *externalEngine
+	if opts.EnginePath != "" {
+		external, err = startExternalEngine(opts.EnginePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer external.close()
+	}
+	p := tea.NewProgram(initialModel(opts, *weights, external))
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("alas, there's been an error: %v", err)
 		os.Exit(1)
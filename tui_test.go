@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	ai "github.com/spunker/chess/ai"
+	"github.com/spunker/chess/game"
+)
+
+func TestBotMoveStale(t *testing.T) {
+	m := initialModel(TuiOptions{Color: "black", Setup: "default", Depth: 1, Skill: -1}, ai.DefaultWeights(), nil)
+	m.menu.botThreads = 1
+	m.inMenu = false
+	var err error
+	if m.game, err = game.StartGame("default"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bot searches a copy, its message is played only in the position it was searched in
+	msg := m.getBotMove(m.game.State, 1)()
+	if len(m.game.State.PreviousMoves) != 0 {
+		t.Fatalf("Expected the search to leave the game alone")
+	}
+	stale := msg.(BotMoveMsg)
+	stale.ply = 1
+	updated, _ := m.Update(stale)
+	if updated.(model).game.Moves != 0 {
+		t.Errorf("Expected a move for another position to be dropped")
+	}
+	updated, _ = m.Update(msg)
+	if updated.(model).game.Moves != 1 {
+		t.Errorf("Expected the bot's move to be played")
+	}
+}
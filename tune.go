@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+// runTune - the tune command, tunes the evaluation weights on a file of labelled positions
+func runTune(args []string) error {
+	flags := newFlagSet("tune")
+	positionsPath := flags.String("positions", "", "file with one labelled position per line (fen followed by the result)")
+	out := flags.String("out", "weights.json", "file the tuned weights are written to (.json or .toml), or a profile name")
+	startProfile := flags.String("start", "", "profile name or weights file to start from (default: the built in weights)")
+	iterations := flags.Int("iterations", 100, "maximum number of passes over all weights")
+	step := flags.Float64("step", 0.05, "how much a weight is nudged at a time")
+	flags.Parse(args)
+
+	if *positionsPath == "" {
+		flags.Usage()
+		return fmt.Errorf("no positions file given")
+	}
+
+	file, err := os.Open(*positionsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	positions, err := ai.ReadLabelledPositions(file)
+	if err != nil {
+		return err
+	}
+
+	start, err := ai.LoadProfile(*startProfile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("tuning on %v positions\n", len(positions))
+	tuned, startErr, endErr, err := ai.Tune(positions, *start, ai.TuneOptions{
+		Iterations: *iterations,
+		Step:       *step,
+		Log:        func(line string) { fmt.Println(line) },
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("error %.6f -> %.6f\n", startErr, endErr)
+
+	path := *out
+	if filepath.Ext(path) == "" {
+		path, err = ai.SaveProfile(*out, &tuned)
+	} else {
+		err = ai.SaveWeights(path, &tuned)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("tuned weights written to %v\n", path)
+	return nil
+}
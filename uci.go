@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+// UCI - the Universal Chess Interface, the engine reads commands from stdin and answers on stdout
+// see https://www.shredderchess.com/chess-features/uci-universal-chess-interface.html
+
+// uciSession - an engine talking UCI
+type uciSession struct {
+	engine *engine
+	out    *protocolWriter
+}
+
+// runUCI - the uci command, speaks UCI on stdin and stdout
+func runUCI(args []string) error {
+	weights, err := protocolWeights("uci", args)
+	if err != nil {
+		return err
+	}
+	session := newUCISession(*weights, os.Stdout)
+	return runProtocol(os.Stdin, session, session.engine)
+}
+
+// newUCISession - creates an engine answering on out
+func newUCISession(weights ai.Weights, out io.Writer) *uciSession {
+	return &uciSession{
+		engine: newEngine(weights),
+		out:    &protocolWriter{w: out},
+	}
+}
+
+// handle - handles a single command, returns false on quit
+func (u *uciSession) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	switch fields[0] {
+	case "uci":
+		u.identify()
+	case "isready":
+		u.out.writeLine("readyok")
+	case "ucinewgame":
+		u.engine.newGame()
+	case "setoption":
+		u.setOption(fields[1:])
+	case "position":
+		u.position(fields[1:])
+	case "go":
+		u.goSearch(fields[1:])
+	case "stop":
+		u.engine.stopSearch()
+	case "quit":
+		u.engine.stopSearch()
+		return false
+	}
+	// anything else is ignored, as the protocol asks
+	return true
+}
+
+// identify - answers uci with the name and the options of the engine
+func (u *uciSession) identify() {
+	u.out.writeLine("id name %v", engineName)
+	u.out.writeLine("id author spunker")
+	u.out.writeLine("option name Hash type spin default %v min 1 max 4096", defaultHash)
+	u.out.writeLine("option name Threads type spin default %v min 1 max %v", u.engine.threads, 4*runtime.NumCPU())
+	u.out.writeLine("option name Quiescence type spin default %v min 0 max 32", defaultQuiescence)
+	// UCI has no option type for fractions, so the weights are strings holding a number
+	for _, name := range ai.WeightNames() {
+		value, _ := u.engine.weights.Weight(name)
+		u.out.writeLine("option name %v type string default %v", name, value)
+	}
+	u.out.writeLine("uciok")
+}
+
+// setOption - setoption name <name> [value <value>], names may contain spaces
+func (u *uciSession) setOption(args []string) {
+	var name, value []string
+	var target *[]string
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			if target != nil {
+				*target = append(*target, arg)
+			}
+		}
+	}
+	optionName := strings.Join(name, " ")
+	optionValue := strings.Join(value, " ")
+
+	switch strings.ToLower(optionName) {
+	case "hash":
+		megabytes, err := strconv.Atoi(optionValue)
+		if err != nil {
+			u.out.writeLine("info string invalid hash size %q", optionValue)
+			return
+		}
+		u.engine.setHash(megabytes)
+	case "threads":
+		threads, err := strconv.Atoi(optionValue)
+		if err != nil || threads < 1 {
+			u.out.writeLine("info string invalid number of threads %q", optionValue)
+			return
+		}
+		u.engine.cancelSearch()
+		u.engine.threads = threads
+	case "quiescence":
+		plies, err := strconv.Atoi(optionValue)
+		if err != nil || plies < 0 {
+			u.out.writeLine("info string invalid quiescence depth %q", optionValue)
+			return
+		}
+		u.engine.cancelSearch()
+		u.engine.quiescence = plies
+	default:
+		weight, err := strconv.ParseFloat(optionValue, 64)
+		if err != nil {
+			u.out.writeLine("info string invalid value %q for option %v", optionValue, optionName)
+			return
+		}
+		if err := u.engine.setWeight(strings.ToLower(optionName), weight); err != nil {
+			u.out.writeLine("info string %v", err)
+		}
+	}
+}
+
+// position - position [startpos | fen <fen>] [moves <move>...]
+func (u *uciSession) position(args []string) {
+	fen, moves := parsePosition(args)
+	if err := u.engine.setPosition(fen, moves); err != nil {
+		u.out.writeLine("info string %v", err)
+	}
+}
+
+// parsePosition - the fen ("" for startpos) and moves of the arguments of a position command
+func parsePosition(args []string) (fen string, moves []string) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "startpos":
+		case "fen":
+			end := i + 1
+			for end < len(args) && args[end] != "moves" {
+				end++
+			}
+			fen = strings.Join(args[i+1:end], " ")
+			i = end - 1
+		case "moves":
+			moves = args[i+1:]
+			i = len(args)
+		}
+	}
+	return fen, moves
+}
+
+// parseGoLimits - the arguments of go, times are in milliseconds
+func parseGoLimits(args []string) (limits searchLimits) {
+	number := func(i int) int {
+		if i+1 >= len(args) {
+			return 0
+		}
+		n, _ := strconv.Atoi(args[i+1])
+		return n
+	}
+	millis := func(i int) time.Duration {
+		return time.Duration(number(i)) * time.Millisecond
+	}
+	for i, arg := range args {
+		switch arg {
+		case "depth":
+			limits.depth = number(i)
+		case "nodes":
+			limits.nodes = number(i)
+		case "movetime":
+			limits.moveTime = millis(i)
+		case "wtime":
+			limits.wtime = millis(i)
+		case "btime":
+			limits.btime = millis(i)
+		case "winc":
+			limits.winc = millis(i)
+		case "binc":
+			limits.binc = millis(i)
+		case "movestogo":
+			limits.movesToGo = number(i)
+		case "infinite":
+			limits.infinite = true
+		}
+	}
+	return
+}
+
+// goSearch - starts a search, the result is reported with bestmove
+func (u *uciSession) goSearch(args []string) {
+	turn := u.engine.game.State.Turn
+	pawn := u.engine.pawnValue()
+	u.engine.search(parseGoLimits(args),
+		func(result *ai.SearchResult) {
+			u.out.writeLine("%v", uciInfo(result, turn, pawn))
+		},
+		func(result *ai.SearchResult, err error) {
+			if err != nil {
+				u.out.writeLine("info string %v", err)
+			}
+			if result == nil || result.Move == nil {
+				u.out.writeLine("bestmove 0000")
+				return
+			}
+			u.out.writeLine("bestmove %v", result.Move.UCI())
+		})
+}
+
+// uciInfo - the info line of a finished depth
+func uciInfo(result *ai.SearchResult, turn string, pawn float64) string {
+	score := fmt.Sprintf("cp %v", centipawns(result.Score, pawn, turn))
+	if result.Mate {
+		mateIn := result.MateIn
+		if turn == "black" {
+			mateIn = -mateIn
+		}
+		score = fmt.Sprintf("mate %v", mateIn)
+	}
+	return fmt.Sprintf("info depth %v seldepth %v score %v nodes %v nps %v time %v pv %v",
+		result.Depth, result.SelDepth, score, result.Nodes, result.NPS, result.Time.Milliseconds(), coordinatePV(result))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+// waitForSearch - waits until the running search reported its move
+func waitForSearch(e *engine) {
+	e.mu.Lock()
+	done := e.done
+	e.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func TestUCI(t *testing.T) {
+	var out bytes.Buffer
+	u := newUCISession(ai.DefaultWeights(), &out)
+	u.engine.threads = 1
+
+	for _, line := range []string{"uci", "isready", "setoption name Hash value 1", "setoption name material value 2.5"} {
+		u.handle(line)
+	}
+	if !strings.Contains(out.String(), "option name Hash type spin") || !strings.Contains(out.String(), "uciok\nreadyok\n") {
+		t.Errorf("Unexpected handshake:\n%v", out.String())
+	}
+	if u.engine.weights.Material != 2.5 {
+		t.Errorf("Expected material weight 2.5, got %v", u.engine.weights.Material)
+	}
+
+	out.Reset()
+	u.handle("position startpos moves e2e4 e7e5")
+	u.handle("go depth 2")
+	waitForSearch(u.engine)
+	if !strings.Contains(out.String(), "info depth 2 ") || !strings.Contains(out.String(), "\nbestmove ") {
+		t.Errorf("Expected an info line and a best move, got:\n%v", out.String())
+	}
+
+	// black to move, mate is reported from the side to move
+	out.Reset()
+	u.handle("position fen 3r2k1/8/8/8/8/8/5PPP/6K1 b - - 0 1")
+	u.handle("go depth 2")
+	waitForSearch(u.engine)
+	if !strings.Contains(out.String(), "score mate 1 ") || !strings.HasSuffix(out.String(), "bestmove d8d1\n") {
+		t.Errorf("Expected mate in 1 with d8d1, got:\n%v", out.String())
+	}
+
+	out.Reset()
+	u.handle("position startpos moves e2e5")
+	if !strings.Contains(out.String(), "info string illegal move") {
+		t.Errorf("Expected an illegal move to be reported, got:\n%v", out.String())
+	}
+	// repetitions are for the GUI to adjudicate, the moves after them are played
+	out.Reset()
+	u.handle("position startpos moves g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8 e2e4")
+	if out.Len() != 0 || u.engine.game.Moves != 9 {
+		t.Errorf("Expected the moves past the repetition to be played, got %v moves:\n%v", u.engine.game.Moves, out.String())
+	}
+	u.handle("position startpos moves e2e5")
+
+	// the session stays invalid until a position can be set up, go must not answer for the old one
+	out.Reset()
+	u.handle("go depth 1")
+	waitForSearch(u.engine)
+	if !strings.HasSuffix(out.String(), "bestmove 0000\n") {
+		t.Errorf("Expected no move after a failed position, got:\n%v", out.String())
+	}
+	out.Reset()
+	u.handle("position startpos")
+	u.handle("go depth 1")
+	waitForSearch(u.engine)
+	if strings.HasSuffix(out.String(), "bestmove 0000\n") {
+		t.Errorf("Expected a move once the position is valid again, got:\n%v", out.String())
+	}
+	if u.handle("quit") {
+		t.Errorf("Expected quit to end the session")
+	}
+}
+
+func TestTimeBudget(t *testing.T) {
+	limits := parseGoLimits(strings.Fields("wtime 60000 btime 1000 winc 1000 binc 0"))
+	if budget := limits.timeBudget("white"); budget.Milliseconds() != 2750 {
+		t.Errorf("Expected 2750ms for white, got %v", budget)
+	}
+	if budget := limits.timeBudget("black"); budget.Milliseconds() != 33 {
+		t.Errorf("Expected 33ms for black, got %v", budget)
+	}
+	if budget := parseGoLimits([]string{"movetime", "500"}).timeBudget("white"); budget.Milliseconds() != 500 {
+		t.Errorf("Expected 500ms, got %v", budget)
+	}
+}
+
+func TestCentipawns(t *testing.T) {
+	// with material alone a pawn up is 100 centipawns, whatever a pawn is worth in the weights
+	for _, material := range []float64{1, 2, 3} {
+		e := newEngine(ai.Weights{Material: material})
+		e.threads = 1
+		if err := e.setPosition("4k3/5pp1/8/8/8/6P1/5PP1/4K3 w - - 0 1", nil); err != nil {
+			t.Fatal(err)
+		}
+		var best *ai.SearchResult
+		e.search(searchLimits{depth: 2}, func(*ai.SearchResult) {}, func(result *ai.SearchResult, err error) {
+			best = result
+		})
+		waitForSearch(e)
+		if best == nil {
+			t.Fatalf("Expected a result with material %v", material)
+		}
+		if white := centipawns(best.Score, e.pawnValue(), "white"); white != 100 {
+			t.Errorf("Expected 100 centipawns with material %v, got %v", material, white)
+		}
+		if black := centipawns(best.Score, e.pawnValue(), "black"); black != -100 {
+			t.Errorf("Expected -100 centipawns for black with material %v, got %v", material, black)
+		}
+	}
+}
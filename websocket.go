@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket - the parts of RFC 6455 the multiplayer server needs
+// see https://www.rfc-editor.org/rfc/rfc6455
+
+// websocketGUID - appended to the client's key to build the accept key of the handshake
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage - larger messages close the connection
+const maxWebSocketMessage = 1 << 20
+
+// websocketWriteTimeout - a peer that doesn't read for this long is dropped
+const websocketWriteTimeout = 5 * time.Second
+
+// opcodes of the frames
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// errWebSocketClosed - the peer closed the connection
+var errWebSocketClosed = errors.New("websocket closed")
+
+// wsConn - the server side of a WebSocket connection, reads happen on one goroutine, writes may come from any
+type wsConn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	wmu    sync.Mutex
+	closed bool
+}
+
+// websocketAccept - the Sec-WebSocket-Accept answering a Sec-WebSocket-Key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains - true if the comma separated header has the token
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// originAllowed - true if a browser on the Origin of the request may connect
+// requests without an Origin don't come from a browser page, others must come from the host they connect to
+// or from one of the allowed hosts ("*" allows any)
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, host := range allowed {
+		if host == "*" || strings.EqualFold(host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket - answers the opening handshake and takes over the connection
+// origins are the hosts besides the server's own whose pages may connect, see originAllowed
+// on error the response was already written
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, origins []string) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case !originAllowed(r, origins):
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("origin not allowed")
+	case r.Method != http.MethodGet:
+		http.Error(w, "websocket handshake must be a GET", http.StatusMethodNotAllowed)
+		return nil, errors.New("not a GET")
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		http.Error(w, "expected a websocket upgrade", http.StatusUpgradeRequired)
+		return nil, errors.New("not a websocket upgrade")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	case key == "":
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %v\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: rw.Reader}, nil
+}
+
+// readFrame - reads a single frame
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, errors.New("websocket: reserved bits set")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, errors.New("websocket: client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.br, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.br, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("websocket: frame of %v bytes is too large", length)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame - writes a single unfragmented frame, servers send them unmasked
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	_, err := c.conn.Write(frame)
+	if opcode == opClose {
+		c.closed = true
+	}
+	return err
+}
+
+// readMessage - the next text or binary message, answers pings and closes on the way
+// returns errWebSocketClosed once the peer closed the connection
+func (c *wsConn) readMessage() (opcode byte, message []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// echo the status code back, as the closing handshake asks
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return 0, nil, errWebSocketClosed
+		case opText, opBinary:
+			if opcode != 0 {
+				return 0, nil, errors.New("websocket: new message inside a fragmented one")
+			}
+			opcode = op
+		case opContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("websocket: continuation without a message")
+			}
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %v", op)
+		}
+		message = append(message, payload...)
+		if len(message) > maxWebSocketMessage {
+			return 0, nil, errors.New("websocket: message too large")
+		}
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+// writeText - sends a text message
+func (c *wsConn) writeText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// close - sends a normal closure and closes the connection
+func (c *wsConn) close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xE8}) // 1000, normal closure
+	return c.conn.Close()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// wsClient - the client side of a WebSocket connection, for talking to the server in tests
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket - opens a client connection to a ws:// url
+func dialWebSocket(url string) (*wsClient, error) {
+	hostPath, ok := strings.CutPrefix(url, "ws://")
+	if !ok {
+		return nil, fmt.Errorf("unsupported websocket url %q", url)
+	}
+	host, path, _ := strings.Cut(hostPath, "/")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	fmt.Fprintf(conn, "GET /%v HTTP/1.1\r\nHost: %v\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %v\r\nSec-WebSocket-Version: 13\r\n\r\n", path, host, key)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake: %v %v", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		conn.Close()
+		return nil, errors.New("websocket handshake: wrong Sec-WebSocket-Accept")
+	}
+	return &wsClient{conn: conn, br: br}, nil
+}
+
+// readMessage - the next message of the server, which sends every message in a single unmasked frame
+func (c *wsClient) readMessage() (opcode byte, message []byte, err error) {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.br, header[:]); err != nil {
+			return 0, nil, err
+		}
+		length := uint64(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(c.br, extended[:]); err != nil {
+				return 0, nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(c.br, extended[:]); err != nil {
+				return 0, nil, err
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return 0, nil, err
+		}
+		switch opcode := header[0] & 0x0F; opcode {
+		case opClose:
+			return 0, nil, errWebSocketClosed
+		case opText, opBinary:
+			return opcode, payload, nil
+		}
+	}
+}
+
+// writeFrame - writes a single frame, masked as clients have to
+func (c *wsClient) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeText - sends a text message
+func (c *wsClient) writeText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// close - sends a normal closure and closes the connection
+func (c *wsClient) close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xE8})
+	return c.conn.Close()
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+// XBoard - the Chess Engine Communication Protocol (version 2)
+// see https://www.gnu.org/software/xboard/engine-intf.html
+// unlike UCI the engine keeps track of the game itself and decides on its own when it is its turn
+
+// xboardSession - an engine talking xboard
+type xboardSession struct {
+	engine *engine
+	out    *protocolWriter
+	force  bool // only play the moves that are sent, don't think
+	post   bool // print the thinking output
+
+	depth     int           // sd, 0 for no limit
+	moveTime  time.Duration // st, fixed time per move
+	movesToGo int           // level: moves per time control (0 = the whole game)
+	increment time.Duration // level: time added after every move
+	clock     time.Duration // time: what is left on the engine's clock
+}
+
+// runXBoard - the xboard command, speaks xboard on stdin and stdout
+func runXBoard(args []string) error {
+	weights, err := protocolWeights("xboard", args)
+	if err != nil {
+		return err
+	}
+	session := newXBoardSession(*weights, os.Stdout)
+	return runProtocol(os.Stdin, session, session.engine)
+}
+
+// newXBoardSession - creates an engine answering on out
+func newXBoardSession(weights ai.Weights, out io.Writer) *xboardSession {
+	return &xboardSession{
+		engine: newEngine(weights),
+		out:    &protocolWriter{w: out},
+	}
+}
+
+// handle - handles a single command, returns false on quit
+func (x *xboardSession) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "protover":
+		x.out.writeLine(`feature myname="%v" ping=1 setboard=1 usermove=1 playother=0 san=0 colors=0 analyze=0 sigint=0 sigterm=0 done=1`, engineName)
+	case "new":
+		x.engine.newGame()
+		x.force = false
+		x.depth, x.moveTime = 0, 0
+	case "force":
+		x.engine.cancelSearch()
+		x.force = true
+	case "go":
+		x.force = false
+		x.think()
+	case "usermove":
+		if len(args) > 0 {
+			x.userMove(args[0])
+		}
+	case "undo":
+		x.undo(1)
+	case "remove":
+		x.undo(2)
+	case "setboard":
+		if err := x.engine.setPosition(strings.Join(args, " "), nil); err != nil {
+			x.out.writeLine("tellusererror Illegal position: %v", err)
+		}
+	case "level":
+		x.level(args)
+	case "st":
+		if len(args) > 0 {
+			seconds, _ := strconv.ParseFloat(args[0], 64)
+			x.moveTime = time.Duration(seconds * float64(time.Second))
+		}
+	case "sd":
+		if len(args) > 0 {
+			x.depth, _ = strconv.Atoi(args[0])
+		}
+	case "time":
+		if len(args) > 0 {
+			centiseconds, _ := strconv.Atoi(args[0])
+			x.clock = time.Duration(centiseconds) * 10 * time.Millisecond
+		}
+	case "post":
+		x.post = true
+	case "nopost":
+		x.post = false
+	case "ping":
+		x.out.writeLine("pong %v", strings.Join(args, " "))
+	case "?":
+		x.engine.stopSearch()
+	case "result":
+		x.engine.cancelSearch()
+		x.force = true
+	case "quit":
+		x.engine.cancelSearch()
+		return false
+	}
+	// xboard, otim, hard, easy, random, computer, name, accepted, rejected ... need no answer
+	return true
+}
+
+// userMove - plays the opponent's move and answers it unless in force mode
+func (x *xboardSession) userMove(str string) {
+	x.engine.cancelSearch()
+	if err := x.engine.game.PlayUCI(str); err != nil {
+		x.out.writeLine("Illegal move: %v", str)
+		return
+	}
+	if x.reportResult() || x.force {
+		return
+	}
+	x.think()
+}
+
+// undo - takes back a number of moves
+func (x *xboardSession) undo(moves int) {
+	x.engine.cancelSearch()
+	for range moves {
+		if _, err := x.engine.game.Undo(); err != nil {
+			x.out.writeLine("tellusererror %v", err)
+			return
+		}
+	}
+}
+
+// level - level <moves per control> <base minutes[:seconds]> <increment seconds>
+func (x *xboardSession) level(args []string) {
+	if len(args) < 3 {
+		return
+	}
+	x.movesToGo, _ = strconv.Atoi(args[0])
+	minutes, seconds, _ := strings.Cut(args[1], ":")
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	x.clock = time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	inc, _ := strconv.ParseFloat(args[2], 64)
+	x.increment = time.Duration(inc * float64(time.Second))
+	x.moveTime = 0
+}
+
+// limits - the search limits of the current time control
+func (x *xboardSession) limits() searchLimits {
+	limits := searchLimits{depth: x.depth, moveTime: x.moveTime}
+	if x.moveTime == 0 && x.clock > 0 {
+		// the clock is the engine's, whichever side it plays
+		limits.wtime, limits.btime = x.clock, x.clock
+		limits.winc, limits.binc = x.increment, x.increment
+		if x.movesToGo > 0 {
+			played := x.engine.game.Moves / 2
+			limits.movesToGo = x.movesToGo - played%x.movesToGo
+		}
+	}
+	return limits
+}
+
+// think - searches the position and plays the move it finds
+func (x *xboardSession) think() {
+	x.engine.cancelSearch()
+	if x.claimDraw() || x.reportResult() {
+		return
+	}
+	turn := x.engine.game.State.Turn
+	game := x.engine.game
+	pawn := x.engine.pawnValue()
+	post := x.post
+	x.engine.search(x.limits(),
+		func(result *ai.SearchResult) {
+			if post {
+				x.out.writeLine("%v", xboardThinking(result, turn, pawn))
+			}
+		},
+		func(result *ai.SearchResult, err error) {
+			if err != nil {
+				x.out.writeLine("tellusererror %v", err)
+				return
+			}
+			if result == nil || result.Move == nil {
+				return
+			}
+			if _, err := game.PlayMove(result.Move); err != nil {
+				x.out.writeLine("tellusererror %v", err)
+				return
+			}
+			x.out.writeLine("move %v", result.Move.UCI())
+			if !x.claimDraw() {
+				x.reportResult()
+			}
+		})
+}
+
+// claimDraw - claims a repetition or fifty-move draw when it's the engine's to claim, returns true if it did
+// moves the GUI sends are played past them, it's up to the GUI (or the engine when it thinks) to end the game
+func (x *xboardSession) claimDraw() bool {
+	if x.engine.game.ClaimDraw() != nil {
+		return false
+	}
+	result, reason := x.engine.game.Result()
+	x.out.writeLine("%v {%v}", result, reason)
+	return true
+}
+
+// reportResult - tells the GUI when the game is over, returns true if it is
+func (x *xboardSession) reportResult() bool {
+	if !x.engine.game.Over {
+		return false
+	}
+	result, reason := x.engine.game.Result()
+	x.out.writeLine("%v {%v}", result, reason)
+	return true
+}
+
+// xboardThinking - a line of thinking output: ply, score (centipawns for the engine), time (centiseconds), nodes and pv
+func xboardThinking(result *ai.SearchResult, turn string, pawn float64) string {
+	score := centipawns(result.Score, pawn, turn)
+	if result.Mate {
+		// mates are reported as 100000 + moves to mate, the convention most GUIs understand
+		mateIn := result.MateIn
+		if turn == "black" {
+			mateIn = -mateIn
+		}
+		if mateIn > 0 {
+			score = 100000 + mateIn
+		} else {
+			score = -100000 + mateIn
+		}
+	}
+	return fmt.Sprintf("%v %v %v %v %v", result.Depth, score, result.Time.Milliseconds()/10, result.Nodes, coordinatePV(result))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	ai "github.com/spunker/chess/ai"
+)
+
+func TestXBoard(t *testing.T) {
+	var out bytes.Buffer
+	x := newXBoardSession(ai.DefaultWeights(), &out)
+	x.engine.threads = 1
+
+	for _, line := range []string{"xboard", "protover 2", "new", "sd 2", "post", "usermove e2e4"} {
+		x.handle(line)
+	}
+	waitForSearch(x.engine)
+	if !strings.Contains(out.String(), "feature ") || !strings.Contains(out.String(), "\n2 ") || !strings.Contains(out.String(), "\nmove ") {
+		t.Errorf("Expected features, thinking output and a move, got:\n%v", out.String())
+	}
+	if x.engine.game.Moves != 2 {
+		t.Errorf("Expected the engine to have answered e2e4, %v moves played", x.engine.game.Moves)
+	}
+
+	out.Reset()
+	x.handle("force")
+	x.handle("usermove e7e5")
+	if !strings.HasPrefix(out.String(), "Illegal move: e7e5") {
+		t.Errorf("Expected e7e5 to be illegal for white, got:\n%v", out.String())
+	}
+	x.handle("remove")
+	if x.engine.game.Moves != 0 || x.engine.game.State.Turn != "white" {
+		t.Errorf("Expected remove to take back both moves, %v moves played", x.engine.game.Moves)
+	}
+
+	// in force mode the moves are played past a repetition, the engine claims it when it is told to think
+	out.Reset()
+	for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"} {
+		x.handle("usermove " + move)
+	}
+	if x.engine.game.Over || out.Len() != 0 {
+		t.Errorf("Expected the game to go on in force mode, got:\n%v", out.String())
+	}
+	x.handle("go")
+	waitForSearch(x.engine)
+	if out.String() != "1/2-1/2 {Threefold repetition}\n" {
+		t.Errorf("Expected the engine to claim the repetition, got:\n%v", out.String())
+	}
+
+	out.Reset()
+	x.handle("setboard 6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+	x.handle("go")
+	waitForSearch(x.engine)
+	if !strings.HasSuffix(out.String(), "move d1d8\n1-0 {White mates}\n") {
+		t.Errorf("Expected d1d8 mate and the result, got:\n%v", out.String())
+	}
+}